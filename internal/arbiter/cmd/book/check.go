@@ -0,0 +1,67 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package book
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/match"
+)
+
+// arbiter book check
+func Check() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check book-file",
+		Short: "Validate an opening book and summarise it",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			game, _ := cmd.Flags().GetString("game")
+			format, _ := cmd.Flags().GetString("format")
+
+			summary, err := match.CheckBook(game, match.OpeningConfig{
+				File:   args[0],
+				Format: format,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, invalid := range summary.Invalid {
+				fmt.Printf("\x1b[31mInvalid Opening\x1b[0m: %v\n", invalid)
+			}
+
+			if len(summary.Invalid) > 0 {
+				fmt.Println()
+			}
+
+			fmt.Printf("Openings:      %d\n", summary.Openings)
+			fmt.Printf("Duplicates:    %d\n", summary.Duplicates)
+			fmt.Printf("Invalid:       %d\n", len(summary.Invalid))
+			fmt.Printf("White to move: %d\n", summary.SideToMove[0])
+			fmt.Printf("Black to move: %d\n", summary.SideToMove[1])
+
+			if len(summary.Invalid) > 0 {
+				return fmt.Errorf("book has %d invalid openings", len(summary.Invalid))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("game", "g", "chess", "Game the book's openings are for")
+	cmd.Flags().StringP("format", "f", "", "Format of the book (epd or pgn)")
+	return cmd
+}
@@ -0,0 +1,101 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package book
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/match"
+)
+
+// arbiter book generate
+func Generate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate an EPD opening book from random playouts",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+
+			var config match.GenerateConfig
+			config.Game, _ = flags.GetString("game")
+			config.FEN, _ = flags.GetString("fen")
+			config.Count, _ = flags.GetInt("count")
+			config.Plies, _ = flags.GetInt("plies")
+			config.EnginePlies, _ = flags.GetInt("engine-plies")
+			config.Seed, _ = flags.GetUint64("seed")
+			config.Threshold, _ = flags.GetInt("threshold")
+
+			// The reference engine does a short search on each opening.
+			if cmdline, _ := flags.GetString("engine"); cmdline != "" {
+				movetime, _ := flags.GetFloat64("movetime")
+
+				engine := match.EngineConfig{Name: "reference", Cmd: cmdline, TimeC: fmt.Sprintf("%g/move", movetime)}
+				engine.Arg, _ = flags.GetString("engine-args")
+				engine.Protocol, _ = flags.GetString("protocol")
+				engine.Depth, _ = flags.GetInt("depth")
+				engine.Nodes, _ = flags.GetInt("nodes")
+				config.Engine = &engine
+			}
+
+			var output io.Writer = os.Stdout
+			if path, _ := flags.GetString("output"); path != "" {
+				file, err := os.Create(path)
+				if err != nil {
+					return err
+				}
+
+				defer file.Close()
+				output = file
+			}
+
+			generated, err := match.GenerateOpenings(config, func(fen string) error {
+				_, err := fmt.Fprintln(output, fen)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if generated < config.Count {
+				logrus.Warnf("only %d of %d openings could be generated", generated, config.Count)
+			}
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringP("game", "g", "chess", "Game to generate openings for")
+	flags.String("fen", "", "Position to generate openings from (default the game's starting position)")
+	flags.IntP("count", "n", 100, "Number of openings to generate")
+	flags.IntP("plies", "p", 8, "Number of random plies to play for each opening")
+	flags.Int("engine-plies", 0, "Number of plies to play with the engine after the random ones")
+	flags.Uint64("seed", 0, "Seed for the random moves (default the current time)")
+	flags.StringP("output", "o", "", "File to write the openings to (default stdout)")
+
+	flags.StringP("engine", "e", "", "Command of the reference engine which filters the openings")
+	flags.String("engine-args", "", "Arguments of the reference engine")
+	flags.String("protocol", "", "Protocol of the reference engine (default uci)")
+	flags.Float64("movetime", 0.1, "Seconds the reference engine searches each opening for")
+	flags.Int("depth", 0, "Depth the reference engine searches each opening to")
+	flags.Int("nodes", 0, "Nodes the reference engine searches for each opening")
+	flags.Int("threshold", 100, "Maximum absolute score, in centipawns, of the openings kept")
+	return cmd
+}
@@ -0,0 +1,60 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+)
+
+func Match() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "match details-file",
+		Short: "Play a fixed number of game pairs between two engines",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			// Matches are configured like tests, and are played the same
+			// way but without a stopping rule.
+			var config sprt.Config
+			err = yaml.Unmarshal(file, &config)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("pairs") {
+				config.Pairs, _ = cmd.Flags().GetInt("pairs")
+			}
+
+			if config.Pairs <= 0 {
+				return errors.New("match: number of game pairs not set")
+			}
+
+			return runSPRT(cmd, config)
+		},
+	}
+
+	sprtFlags(cmd)
+	cmd.Flags().Int("pairs", 0, "Number of game pairs to play, overriding the details file")
+	return cmd
+}
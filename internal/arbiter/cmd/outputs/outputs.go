@@ -0,0 +1,85 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package outputs implements the flags shared by the commands which run
+// tests and tournaments, which choose where their results are reported.
+package outputs
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/dashboard"
+	"laptudirm.com/x/arbiter/pkg/eve/events"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
+)
+
+// Outputs are the event stream, the database, and the dashboard a test or a
+// tournament reports its results to, each of which may be nil.
+type Outputs struct {
+	Events    *events.Stream
+	Store     *store.Store
+	Dashboard *dashboard.Dashboard
+}
+
+// Flags adds the flags used by Open to the given command, including the
+// one for the dashboard if tui is set.
+func Flags(cmd *cobra.Command, tui bool) {
+	cmd.Flags().String("events", "", "File to stream JSON events to, or - for stdout")
+	cmd.Flags().Bool("event-moves", false, "Include every move in the event stream")
+	if tui {
+		cmd.Flags().Bool("tui", false, "Show a live dashboard instead of the plain output")
+	}
+
+	cmd.Flags().String("db", store.DefaultPath, "Database to store the games in")
+	cmd.Flags().Bool("no-db", false, "Don't store the games in a database")
+}
+
+// Open opens the outputs given by the flags added by Flags. The dashboard
+// is shown with the given name and number of threads.
+func Open(cmd *cobra.Command, name string, threads int) (Outputs, error) {
+	var outputs Outputs
+
+	var err error
+	if path, _ := cmd.Flags().GetString("events"); path != "" {
+		moves, _ := cmd.Flags().GetBool("event-moves")
+		if outputs.Events, err = events.Open(path, moves); err != nil {
+			return outputs, err
+		}
+	}
+
+	if noDB, _ := cmd.Flags().GetBool("no-db"); !noDB {
+		path, _ := cmd.Flags().GetString("db")
+		if outputs.Store, err = store.Open(path); err != nil {
+			outputs.Close()
+			return Outputs{}, err
+		}
+	}
+
+	// The dashboard is only shown on terminals, and not when the event
+	// stream is written to the standard output.
+	if tui, _ := cmd.Flags().GetBool("tui"); tui {
+		if stream, _ := cmd.Flags().GetString("events"); stream != "-" && dashboard.Supported() {
+			outputs.Dashboard = dashboard.Start(name, threads)
+		} else {
+			logrus.Warn("dashboard not supported, falling back to plain output")
+		}
+	}
+
+	return outputs, nil
+}
+
+// Close stops the dashboard and closes the event stream.
+func (outputs Outputs) Close() {
+	outputs.Dashboard.Stop()
+	outputs.Events.Close()
+}
@@ -0,0 +1,62 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"laptudirm.com/x/arbiter/pkg/eve/queue"
+	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+)
+
+// arbiter queue add
+func Add() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add details-file",
+		Short: "Add a test to the queue",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			var config sprt.Config
+			if err := yaml.Unmarshal(file, &config); err != nil {
+				return err
+			}
+
+			if name, _ := cmd.Flags().GetString("name"); name != "" {
+				config.Name = name
+			}
+
+			priority, _ := cmd.Flags().GetInt("priority")
+			entry, err := queue.Add(config, priority)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Queued \x1b[34m%s\x1b[0m with priority %d\n", entry.Name, entry.Priority)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntP("priority", "p", 0, "Priority of the test, higher priorities are run first")
+	cmd.Flags().String("name", "", "Name of the test, overriding the details file")
+	return cmd
+}
@@ -0,0 +1,59 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/queue"
+)
+
+// arbiter queue list
+func List() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the tests in the queue and their status",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := queue.List()
+			if err != nil {
+				return err
+			}
+
+			if len(entries) == 0 {
+				fmt.Println("\x1b[31mNo Tests Queued.\x1b[0m")
+				return nil
+			}
+
+			for _, entry := range entries {
+				status := string(entry.Status)
+				switch entry.Status {
+				case queue.Finished:
+					status += " " + entry.Conclusion
+				case queue.Failed:
+					status += ": " + entry.Error
+				}
+
+				fmt.Printf(
+					"%s  %3d  \x1b[34m%-30s\x1b[0m  %s\n",
+					entry.Added.Format("2006-01-02 15:04"), entry.Priority, entry.Name, status,
+				)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/queue"
+)
+
+// arbiter queue remove
+func Remove() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove test-name",
+		Short: "Remove a test which isn't running from the queue",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return queue.Remove(args[0])
+		},
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/queue"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
+)
+
+// arbiter queue run
+func Run() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the tests in the queue as they are added",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var runner queue.Runner
+			runner.Tests, _ = cmd.Flags().GetInt("tests")
+			runner.Workers, _ = cmd.Flags().GetInt("workers")
+			runner.Poll, _ = cmd.Flags().GetDuration("poll")
+
+			if noDB, _ := cmd.Flags().GetBool("no-db"); !noDB {
+				path, _ := cmd.Flags().GetString("db")
+
+				var err error
+				if runner.Store, err = store.Open(path); err != nil {
+					return err
+				}
+			}
+
+			return runner.Run()
+		},
+	}
+
+	cmd.Flags().Int("tests", 1, "Number of tests to run at the same time")
+	cmd.Flags().Int("workers", 0, "Number of game pairs played at once by all the tests, shared by priority (default each test's concurrency)")
+	cmd.Flags().Duration("poll", 10*time.Second, "Interval at which the queue is checked for new tests")
+	cmd.Flags().String("db", store.DefaultPath, "Database to store the games in")
+	cmd.Flags().Bool("no-db", false, "Don't store the games in a database")
+	return cmd
+}
@@ -0,0 +1,83 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/report"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
+)
+
+func Report() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report run-name",
+		Short: "Generate a report of a run from its stored games",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			html, _ := cmd.Flags().GetString("html")
+			if html == "" {
+				return errors.New("report: no output file, use --html")
+			}
+
+			path, _ := cmd.Flags().GetString("db")
+			db, err := store.Open(path)
+			if err != nil {
+				return err
+			}
+
+			// Runs which haven't finished don't have a record, but can still
+			// be reported on from their games.
+			run, err := db.Run(args[0])
+			if errors.Is(err, store.ErrNoRun) {
+				run = store.RunRecord{Name: args[0]}
+			} else if err != nil {
+				return err
+			}
+
+			games, err := db.Games(store.Run(args[0]))
+			if err != nil {
+				return err
+			}
+
+			if len(games) == 0 {
+				return fmt.Errorf("report: no games found for %s", args[0])
+			}
+
+			if run.Kind == "" {
+				run.Kind, run.Game = games[0].Kind, games[0].Game
+			}
+
+			if html == "-" {
+				return report.Build(run, games).WriteHTML(os.Stdout)
+			}
+
+			file, err := os.Create(html)
+			if err != nil {
+				return err
+			}
+
+			defer file.Close()
+			return report.Build(run, games).WriteHTML(file)
+		},
+	}
+
+	cmd.Flags().String("db", store.DefaultPath, "Database the run is stored in")
+	cmd.Flags().String("html", "", "File to write the HTML report to, or - for stdout")
+	return cmd
+}
@@ -19,12 +19,14 @@ import (
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"laptudirm.com/x/arbiter/internal/arbiter/cmd/outputs"
 	arbiter "laptudirm.com/x/arbiter/pkg/common"
 	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
 )
 
 func SPRT() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "sprt test-name",
 		Short: "Restart a Sequential Probability Ratio Test",
 		Args:  cobra.ExactArgs(1),
@@ -46,7 +48,30 @@ func SPRT() *cobra.Command {
 				return err
 			}
 
+			out, err := outputs.Open(cmd, tour.Name, tour.Config.Concurrency)
+			if err != nil {
+				return err
+			}
+
+			defer out.Close()
+			tour.Events, tour.Store, tour.Dashboard = out.Events, out.Store, out.Dashboard
+
+			// The test's state is rebuilt from its stored games, if any.
+			if tour.Store != nil {
+				games, err := tour.Store.Games(store.Run(tour.Name))
+				if err != nil {
+					return err
+				}
+
+				if len(games) > 0 {
+					tour.Restore(games)
+				}
+			}
+
 			return tour.Start()
 		},
 	}
+
+	outputs.Flags(cmd, true)
+	return cmd
 }
@@ -19,12 +19,14 @@ import (
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"laptudirm.com/x/arbiter/internal/arbiter/cmd/outputs"
 	arbiter "laptudirm.com/x/arbiter/pkg/common"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
 	"laptudirm.com/x/arbiter/pkg/eve/tournament"
 )
 
 func Tournament() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "tour test-name",
 		Short: "Restart a stopped engine tournament",
 		Args:  cobra.ExactArgs(1),
@@ -46,7 +48,29 @@ func Tournament() *cobra.Command {
 				return err
 			}
 
+			out, err := outputs.Open(cmd, tour.Config.Name, tour.Config.Concurrency)
+			if err != nil {
+				return err
+			}
+
+			defer out.Close()
+			tour.Events, tour.Store, tour.Dashboard = out.Events, out.Store, out.Dashboard
+
+			// The games which were already played are restored from the
+			// database instead of being played again.
+			if tour.Store != nil {
+				games, err := tour.Store.Games(store.Run(tour.Config.Name))
+				if err != nil {
+					return err
+				}
+
+				tour.Restore(games)
+			}
+
 			return tour.Start()
 		},
 	}
+
+	outputs.Flags(cmd, true)
+	return cmd
 }
@@ -0,0 +1,205 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
+)
+
+func Results() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "results [run-name [other-run-name]]",
+		Short: "List finished runs, or show or compare their results",
+		Args:  cobra.MaximumNArgs(2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("db")
+			db, err := store.Open(path)
+			if err != nil {
+				return err
+			}
+
+			runs := make([]store.RunRecord, len(args))
+			for i, name := range args {
+				if runs[i], err = db.Run(name); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+
+			switch len(runs) {
+			case 1:
+				printRun(runs[0])
+				return nil
+			case 2:
+				compareRuns(runs[0], runs[1])
+				return nil
+			}
+
+			engine, _ := cmd.Flags().GetString("engine")
+			kind, _ := cmd.Flags().GetString("kind")
+
+			all, err := db.Runs()
+			if err != nil {
+				return err
+			}
+
+			found := false
+			for _, run := range all {
+				if (engine != "" && !run.HasEngine(engine)) || (kind != "" && run.Kind != kind) {
+					continue
+				}
+
+				found = true
+				fmt.Printf(
+					"%s  %-10s  \x1b[34m%-30s\x1b[0m  %5d games  %s\n",
+					run.Finished.Format("2006-01-02 15:04"), run.Kind, run.Name, run.Games, summary(run),
+				)
+			}
+
+			if !found {
+				fmt.Println("\x1b[31mNo Runs Found.\x1b[0m")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("db", store.DefaultPath, "Database the runs are stored in")
+	cmd.Flags().StringP("engine", "e", "", "Only list runs with an engine whose name or version contains this")
+	cmd.Flags().StringP("kind", "k", "", "Only list runs of this kind (sprt, match or tournament)")
+	return cmd
+}
+
+// summary returns a one line summary of the results of the given run.
+func summary(run store.RunRecord) string {
+	if run.SPRT != nil {
+		engine := run.Engines[0]
+		return fmt.Sprintf(
+			"%s accepted, %s vs %s: %+.2f +- %.2f",
+			run.SPRT.Conclusion, engine.Name, run.Engines[1].Name, engine.Elo, engine.Error,
+		)
+	}
+
+	if run.Match != nil {
+		return fmt.Sprintf(
+			"%s vs %s: %+.2f +- %.2f (penta), LOS %.2f%%",
+			run.Engines[0].Name, run.Engines[1].Name,
+			run.Match.PentaElo, run.Match.PentaError, 100*run.Match.LOS,
+		)
+	}
+
+	if len(run.Engines) == 0 {
+		return ""
+	}
+
+	best := 0
+	for i, engine := range run.Engines {
+		if engine.Elo > run.Engines[best].Elo {
+			best = i
+		}
+	}
+
+	return fmt.Sprintf("%d engines, best %s: %+.0f", len(run.Engines), run.Engines[best].Name, run.Engines[best].Elo)
+}
+
+// printRun prints the final report of the given run.
+func printRun(run store.RunRecord) {
+	fmt.Printf("Name:     %s\n", run.Name)
+	fmt.Printf("Kind:     %s\n", run.Kind)
+	fmt.Printf("Game:     %s\n", run.Game)
+	fmt.Printf("Started:  %s\n", run.Started.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Finished: %s\n", run.Finished.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Games:    %d\n", run.Games)
+	if run.TimeOdds != 0 {
+		fmt.Printf("Odds:     %gx time for %s\n", run.TimeOdds, run.Engines[0].Name)
+	}
+
+	fmt.Println()
+
+	if run.SPRT != nil {
+		test := run.SPRT
+		fmt.Printf("ELO   | %.2f +- %.2f (95%%)\n", run.Engines[0].Elo, run.Engines[0].Error)
+		fmt.Printf("LLR   | %.2f (%.2f, %.2f) [%.2f, %.2f]\n", test.LLR, test.Lower, test.Upper, test.Elo0, test.Elo1)
+		fmt.Printf("PENTA | %v\n", test.Penta)
+		fmt.Printf("%s accepted\n", test.Conclusion)
+		fmt.Println()
+	}
+
+	if run.Match != nil {
+		match := run.Match
+		fmt.Printf("ELO   | %.2f +- %.2f (95%%)\n", run.Engines[0].Elo, run.Engines[0].Error)
+		fmt.Printf("PELO  | %.2f +- %.2f (95%%)\n", match.PentaElo, match.PentaError)
+		fmt.Printf("NELO  | %.2f +- %.2f (95%%)\n", match.NElo, match.NEloError)
+		fmt.Printf("LOS   | %.2f%%\n", 100*match.LOS)
+		fmt.Printf("DRAWS | %.2f%%\n", 100*match.Draws)
+		fmt.Printf("PENTA | %v\n", match.Penta)
+		fmt.Println()
+	}
+
+	fmt.Println("    Name                 Version              Elo Error   Wins Loss Draw   TC")
+	for i, engine := range run.Engines {
+		fmt.Printf(
+			"%2d. %-20s %-20s %+4.0f %4.0f   %4d %4d %4d   %s\n",
+			i+1, engine.Name, engine.ID, engine.Elo, engine.Error,
+			engine.Wins, engine.Losses, engine.Draws, engine.TimeC,
+		)
+	}
+}
+
+// compareRuns prints the results of the engines in the given runs side by
+// side, matching the engines by their names.
+func compareRuns(a, b store.RunRecord) {
+	fmt.Printf("%-20s %22s %22s\n", "", a.Name, b.Name)
+	fmt.Printf("%-20s %22d %22d\n", "Games", a.Games, b.Games)
+
+	if a.SPRT != nil && b.SPRT != nil {
+		fmt.Printf("%-20s %22.2f %22.2f\n", "LLR", a.SPRT.LLR, b.SPRT.LLR)
+		fmt.Printf("%-20s %22s %22s\n", "Conclusion", a.SPRT.Conclusion, b.SPRT.Conclusion)
+	}
+
+	if a.Match != nil && b.Match != nil {
+		penta := func(match *store.MatchRecord) string {
+			return fmt.Sprintf("%+.1f +- %.1f", match.PentaElo, match.PentaError)
+		}
+
+		fmt.Printf("%-20s %22s %22s\n", "Penta Elo", penta(a.Match), penta(b.Match))
+		fmt.Printf("%-20s %21.2f%% %21.2f%%\n", "LOS", 100*a.Match.LOS, 100*b.Match.LOS)
+		fmt.Printf("%-20s %21.2f%% %21.2f%%\n", "Draws", 100*a.Match.Draws, 100*b.Match.Draws)
+	}
+
+	elo := func(run store.RunRecord, name string) string {
+		for _, engine := range run.Engines {
+			if engine.Name == name {
+				return fmt.Sprintf("%+.1f +- %.1f", engine.Elo, engine.Error)
+			}
+		}
+
+		return "-"
+	}
+
+	seen := map[string]bool{}
+	for _, run := range []store.RunRecord{a, b} {
+		for _, engine := range run.Engines {
+			if seen[engine.Name] {
+				continue
+			}
+
+			seen[engine.Name] = true
+			fmt.Printf("%-20s %22s %22s\n", engine.Name, elo(a, engine.Name), elo(b, engine.Name))
+		}
+	}
+}
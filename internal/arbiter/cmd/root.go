@@ -51,7 +51,15 @@ func Root() *cobra.Command {
 	root.AddCommand(Remove())
 	root.AddCommand(Tournament())
 	root.AddCommand(SPRT())
+	root.AddCommand(Match())
 	root.AddCommand(Restart())
+	root.AddCommand(Queue())
+	root.AddCommand(Serve())
+	root.AddCommand(Worker())
+	root.AddCommand(Book())
+	root.AddCommand(Results())
+	root.AddCommand(Report())
+	root.AddCommand(Stats())
 
 	return root
 }
@@ -0,0 +1,111 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"laptudirm.com/x/arbiter/internal/arbiter/cmd/outputs"
+	"laptudirm.com/x/arbiter/pkg/eve/remote"
+	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+)
+
+func Serve() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve details-file",
+		Short: "Run a test whose games are played by workers on other machines",
+		Long: `Run a test whose games are played by workers on other machines
+
+The test's game pairs are leased to workers started with 'arbiter worker',
+which play them with their own engines and report their results back. The
+server doesn't authenticate its workers, so it should only be reachable
+from a trusted local network.`,
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			var config sprt.Config
+			err = yaml.Unmarshal(file, &config)
+			if err != nil {
+				return err
+			}
+
+			// The server only plays games itself if it is asked to.
+			config.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+
+			tour, err := sprt.NewTournament(config)
+			if err != nil {
+				return err
+			}
+
+			out, err := outputs.Open(cmd, tour.Name, 0)
+			if err != nil {
+				return err
+			}
+
+			defer out.Close()
+			tour.Events, tour.Store = out.Events, out.Store
+
+			addr, _ := cmd.Flags().GetString("addr")
+			batch, _ := cmd.Flags().GetInt("batch")
+			lease, _ := cmd.Flags().GetDuration("lease")
+
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+
+			server := &http.Server{Handler: remote.NewServer(tour, batch, lease)}
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logrus.Errorf("serve: %v", err)
+				}
+			}()
+
+			logrus.Infof("serving %s on %s", tour.Name, listener.Addr())
+			if err := tour.Start(); err != nil {
+				return err
+			}
+
+			// The server keeps answering for a while after the test ends, so
+			// that the workers asking it for pairs are told to stop.
+			grace, _ := cmd.Flags().GetDuration("grace")
+			time.Sleep(grace)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return server.Shutdown(ctx)
+		},
+	}
+
+	outputs.Flags(cmd, false)
+	cmd.Flags().String("addr", ":8080", "Address to listen for workers on")
+	cmd.Flags().Int("batch", 4, "Maximum number of game pairs leased to a worker at once")
+	cmd.Flags().Duration("lease", 30*time.Minute, "Time a worker has to report its pairs before they are leased to another")
+	cmd.Flags().Duration("grace", 15*time.Second, "Time to keep telling workers that the test has ended before exiting")
+	cmd.Flags().Int("concurrency", 0, "Number of game pairs played at once by the server itself")
+	return cmd
+}
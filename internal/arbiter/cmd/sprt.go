@@ -18,11 +18,13 @@ import (
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"laptudirm.com/x/arbiter/internal/arbiter/cmd/outputs"
+	sprtcmd "laptudirm.com/x/arbiter/internal/arbiter/cmd/sprt"
 	"laptudirm.com/x/arbiter/pkg/eve/sprt"
 )
 
 func SPRT() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "sprt details-file",
 		Short: "Run a Sequential Probability Ratio Test",
 		Args:  cobra.ExactArgs(1),
@@ -39,12 +41,36 @@ func SPRT() *cobra.Command {
 				return err
 			}
 
-			tour, err := sprt.NewTournament(config)
-			if err != nil {
-				return err
-			}
-
-			return tour.Start()
+			return runSPRT(cmd, config)
 		},
 	}
+
+	sprtFlags(cmd)
+
+	cmd.AddCommand(sprtcmd.Estimate())
+	return cmd
+}
+
+// runSPRT runs a test with the given config, with the options given by the
+// flags added by sprtFlags.
+func runSPRT(cmd *cobra.Command, config sprt.Config) error {
+	tour, err := sprt.NewTournament(config)
+	if err != nil {
+		return err
+	}
+
+	out, err := outputs.Open(cmd, tour.Name, tour.Config.Concurrency)
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+	tour.Events, tour.Store, tour.Dashboard = out.Events, out.Store, out.Dashboard
+
+	return tour.Start()
+}
+
+// sprtFlags adds the flags used by runSPRT to the given command.
+func sprtFlags(cmd *cobra.Command) {
+	outputs.Flags(cmd, true)
 }
@@ -0,0 +1,151 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sprt
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+	"laptudirm.com/x/arbiter/pkg/eve/stats"
+)
+
+// arbiter sprt estimate
+func Estimate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "estimate [details-file]",
+		Short: "Estimate the number of games an SPRT will take to conclude",
+		Args:  cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The test's hypotheses are taken from its details file, if
+			// any, and can be overridden by the flags.
+			var config sprt.Config
+			if len(args) == 1 {
+				file, err := os.ReadFile(args[0])
+				if err != nil {
+					return err
+				}
+
+				if err := yaml.Unmarshal(file, &config); err != nil {
+					return err
+				}
+			}
+
+			flags := cmd.Flags()
+			for name, value := range map[string]*float64{
+				"elo0": &config.Elo0, "elo1": &config.Elo1,
+				"alpha": &config.Alpha, "beta": &config.Beta,
+			} {
+				if flags.Changed(name) || len(args) == 0 {
+					*value, _ = flags.GetFloat64(name)
+				}
+			}
+
+			if flags.Changed("legacy") {
+				config.Legacy, _ = flags.GetBool("legacy")
+			}
+
+			if config.Elo0 >= config.Elo1 {
+				return fmt.Errorf("estimate: elo0 %.2f is not less than elo1 %.2f", config.Elo0, config.Elo1)
+			}
+
+			draws, _ := flags.GetFloat64("draws")
+			if draws < 0 || draws >= 1 {
+				return fmt.Errorf("estimate: draw ratio %.2f is not in [0, 1)", draws)
+			}
+
+			elos, _ := flags.GetFloat64Slice("elo")
+			if len(elos) == 0 {
+				elos = []float64{config.Elo0, (config.Elo0 + config.Elo1) / 2, config.Elo1}
+			}
+
+			rate, _ := flags.GetFloat64("rate")
+
+			fmt.Printf("SPRT [%.2f, %.2f] alpha %.2f beta %.2f", config.Elo0, config.Elo1, config.Alpha, config.Beta)
+			if config.Legacy {
+				fmt.Print(" (legacy)")
+			}
+
+			fmt.Printf(", %.0f%% draws\n\n", 100*draws)
+			fmt.Println("      Elo      H0      H1       Games   Duration")
+
+			for _, elo := range elos {
+				estimate := stats.PlanEstimate(elo, draws, config.Elo0, config.Elo1, config.Alpha, config.Beta, config.Legacy)
+				fmt.Printf("%9.2f  %s\n", elo, estimateRow(estimate, rate))
+			}
+
+			// Tests which have been started are also estimated from their
+			// results so far.
+			state := config.State
+			if games := state.Wins + state.Losses + state.Draws; games > 0 {
+				lower, upper := stats.StoppingBounds(config.Alpha, config.Beta)
+
+				var estimate stats.Estimate
+				if config.Legacy {
+					llr := stats.SPRT(state.Wins, state.Draws, state.Losses, config.Elo0, config.Elo1)
+					estimate = stats.SPRTEstimate(
+						state.Wins, state.Draws, state.Losses,
+						config.Elo0, config.Elo1,
+						llr, lower, upper,
+					)
+				} else {
+					llr := stats.PentaSPRT(
+						state.LossLoss, state.DrawLoss,
+						state.DrawDraw,
+						state.WinDraw, state.WinWin,
+						config.Elo0, config.Elo1,
+					)
+					estimate = stats.PentaEstimate(
+						state.LossLoss, state.DrawLoss,
+						state.DrawDraw,
+						state.WinDraw, state.WinWin,
+						config.Elo0, config.Elo1,
+						llr, lower, upper,
+					)
+				}
+
+				fmt.Printf("\nFrom the %d games played so far:\n", games)
+				fmt.Printf("%9s  %s\n", "measured", estimateRow(estimate, rate))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Float64("elo0", 0, "Elo of the null hypothesis")
+	cmd.Flags().Float64("elo1", 5, "Elo of the alternate hypothesis")
+	cmd.Flags().Float64("alpha", 0.05, "Probability of a type I error")
+	cmd.Flags().Float64("beta", 0.05, "Probability of a type II error")
+	cmd.Flags().Bool("legacy", false, "Estimate a trinomial instead of a pentanomial test")
+	cmd.Flags().Float64("draws", 0.6, "Expected ratio of drawn games")
+	cmd.Flags().Float64Slice("elo", nil, "True elo differences to estimate the test for (default elo0, midpoint, elo1)")
+	cmd.Flags().Float64("rate", 0, "Games played per minute, to estimate the test's duration")
+	return cmd
+}
+
+// estimateRow returns a row of the estimate table for the given estimate,
+// with its duration if the rate at which games are played is known.
+func estimateRow(estimate stats.Estimate, rate float64) string {
+	duration := "-"
+	if rate > 0 {
+		minutes := estimate.Games / rate
+		duration = time.Duration(minutes * float64(time.Minute)).Round(time.Minute).String()
+	}
+
+	return fmt.Sprintf("%5.1f%%  %5.1f%%  %10.0f   %s", 100*estimate.H0, 100*estimate.H1, estimate.Games, duration)
+}
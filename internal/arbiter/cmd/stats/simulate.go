@@ -0,0 +1,143 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/stats"
+)
+
+// arbiter stats simulate
+func Simulate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate SPRTs to find their pass probability and length",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+
+			var simulation stats.Simulation
+			simulation.Elo0, _ = flags.GetFloat64("elo0")
+			simulation.Elo1, _ = flags.GetFloat64("elo1")
+			simulation.Alpha, _ = flags.GetFloat64("alpha")
+			simulation.Beta, _ = flags.GetFloat64("beta")
+			simulation.Legacy, _ = flags.GetBool("legacy")
+			simulation.Runs, _ = flags.GetInt("runs")
+			simulation.MaxGames, _ = flags.GetInt("max-games")
+			simulation.Seed, _ = flags.GetInt64("seed")
+			simulation.Penta, _ = flags.GetFloat64Slice("penta")
+
+			draws, _ := flags.GetFloat64("draws")
+			histogram, _ := flags.GetBool("histogram")
+
+			elos, _ := flags.GetFloat64Slice("elo")
+			if len(elos) == 0 {
+				elos = []float64{
+					simulation.Elo0,
+					(simulation.Elo0 + simulation.Elo1) / 2,
+					simulation.Elo1,
+				}
+			}
+
+			// A given pair distribution fixes the engines' strength, so
+			// there is only one test to simulate.
+			rows := make([]string, len(elos))
+			for i, elo := range elos {
+				rows[i] = fmt.Sprintf("%9.2f", elo)
+			}
+
+			if len(simulation.Penta) > 0 {
+				rows = []string{"    penta"}
+			} else {
+				simulation.Penta = nil
+			}
+
+			fmt.Printf(
+				"SPRT [%.2f, %.2f] alpha %.2f beta %.2f, %d runs\n\n",
+				simulation.Elo0, simulation.Elo1, simulation.Alpha, simulation.Beta, simulation.Runs,
+			)
+			fmt.Println("      Elo    Pass   Unfinished       Mean        5%       25%       50%       75%       95%")
+
+			for i, row := range rows {
+				if simulation.Penta == nil {
+					var err error
+					if simulation.WDL, err = stats.SimulationWDL(elos[i], draws, simulation.Legacy); err != nil {
+						return err
+					}
+				}
+
+				result, err := simulation.Run()
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf(
+					"%s  %5.1f%%  %10d  %9.0f %9d %9d %9d %9d %9d\n",
+					row, 100*result.PassRate(), result.Unfinished, result.MeanGames(),
+					result.Percentile(0.05), result.Percentile(0.25), result.Percentile(0.50),
+					result.Percentile(0.75), result.Percentile(0.95),
+				)
+
+				if histogram {
+					printHistogram(result.Games, 10, 40)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Float64("elo0", 0, "Elo of the null hypothesis")
+	cmd.Flags().Float64("elo1", 5, "Elo of the alternate hypothesis")
+	cmd.Flags().Float64("alpha", 0.05, "Probability of a type I error")
+	cmd.Flags().Float64("beta", 0.05, "Probability of a type II error")
+	cmd.Flags().Bool("legacy", false, "Simulate a trinomial instead of a pentanomial test")
+	cmd.Flags().Float64Slice("elo", nil, "True elo differences to simulate the test for (default elo0, midpoint, elo1)")
+	cmd.Flags().Float64("draws", 0.6, "Ratio of drawn games")
+	cmd.Flags().Float64Slice("penta", nil, "Probabilities of the game pair results, from loss-loss to win-win, instead of an elo and draw ratio")
+	cmd.Flags().Int("runs", 1000, "Number of tests to simulate for each elo")
+	cmd.Flags().Int("max-games", 0, "Number of games after which a test is stopped (default unlimited)")
+	cmd.Flags().Int64("seed", 1, "Seed of the random number generator")
+	cmd.Flags().Bool("histogram", false, "Print a histogram of the number of games the tests took")
+	return cmd
+}
+
+// printHistogram prints a histogram of the given sorted numbers of games,
+// with the given number of bins, and bars of at most the given width.
+func printHistogram(games []int, bins, width int) {
+	low, high := games[0], games[len(games)-1]
+	size := (high - low + bins) / bins
+
+	counts := make([]int, bins)
+	most := 0
+	for _, n := range games {
+		bin := (n - low) / size
+		counts[bin]++
+		if counts[bin] > most {
+			most = counts[bin]
+		}
+	}
+
+	fmt.Println()
+	for i, count := range counts {
+		bar := strings.Repeat("█", count*width/most)
+		fmt.Printf("%9d - %-9d %5d %s\n", low+i*size, low+(i+1)*size-1, count, bar)
+	}
+
+	fmt.Println()
+}
@@ -18,14 +18,21 @@ import (
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"laptudirm.com/x/arbiter/internal/arbiter/cmd/outputs"
 	"laptudirm.com/x/arbiter/pkg/eve/tournament"
 )
 
 func Tournament() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "tournament details-file",
 		Short: "Run a tournament with different engines",
-		Args:  cobra.ExactArgs(1),
+		Long: `Run a tournament with different engines
+
+The time-odds option of the details file multiplies the time control of
+the first engine by the given ratio, in all of its games. Only the first
+engine is handicapped, and the other engines play each other with their
+own time controls.`,
+		Args: cobra.ExactArgs(1),
 
 		RunE: func(cmd *cobra.Command, args []string) error {
 			file, err := os.ReadFile(args[0])
@@ -44,6 +51,14 @@ func Tournament() *cobra.Command {
 				return err
 			}
 
+			out, err := outputs.Open(cmd, tour.Config.Name, tour.Config.Concurrency)
+			if err != nil {
+				return err
+			}
+
+			defer out.Close()
+			tour.Events, tour.Store, tour.Dashboard = out.Events, out.Store, out.Dashboard
+
 			return tour.Start()
 
 			// var rr tournament.RoundRobin
@@ -59,4 +74,7 @@ func Tournament() *cobra.Command {
 			// return nil
 		},
 	}
+
+	outputs.Flags(cmd, true)
+	return cmd
 }
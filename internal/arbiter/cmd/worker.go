@@ -0,0 +1,86 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"laptudirm.com/x/arbiter/pkg/eve/remote"
+	"laptudirm.com/x/arbiter/pkg/manager"
+)
+
+func Worker() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "worker server-url",
+		Short: "Play the games of a test run by 'arbiter serve'",
+		Long: `Play the games of a test run by 'arbiter serve'
+
+The engines are run with the commands given by --engine, or with the
+engines of the same names installed with arbiter. The server can't make
+the worker run any other command, so tests with engines which aren't
+available are refused. Engine options which aren't numbers or booleans,
+and which may be paths, are ignored for the same reason.`,
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			worker := remote.Worker{
+				URL:     args[0],
+				Engines: map[string]string{},
+			}
+
+			if !strings.Contains(worker.URL, "://") {
+				worker.URL = "http://" + worker.URL
+			}
+
+			worker.Name, _ = cmd.Flags().GetString("name")
+			if worker.Name == "" {
+				worker.Name, _ = os.Hostname()
+			}
+
+			worker.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+			worker.Poll, _ = cmd.Flags().GetDuration("poll")
+
+			// Engines installed with arbiter are used for the engines with
+			// the same names, unless they are given explicitly.
+			for name, info := range manager.Engines {
+				if info.Current != "" {
+					worker.Engines[name] = filepath.Join(manager.BinaryDirectory, name)
+				}
+			}
+
+			engines, _ := cmd.Flags().GetStringArray("engine")
+			for _, engine := range engines {
+				name, command, found := strings.Cut(engine, "=")
+				if !found {
+					return fmt.Errorf("worker: engine %q is not of the form name=cmd", engine)
+				}
+
+				worker.Engines[name] = command
+			}
+
+			return worker.Run()
+		},
+	}
+
+	cmd.Flags().String("name", "", "Name the worker identifies itself with (default hostname)")
+	cmd.Flags().Int("concurrency", 1, "Number of game pairs to play at once")
+	cmd.Flags().StringArray("engine", nil, "Command to run an engine with, as name=cmd (repeatable)")
+	cmd.Flags().Duration("poll", 10*time.Second, "Interval at which the server is asked for games when it has none")
+	return cmd
+}
@@ -43,4 +43,5 @@ func init() {
 	TryMkdir(filepath.Join(Directory, "paused"))
 	TryMkdir(filepath.Join(Directory, "paused", "sprt"))
 	TryMkdir(filepath.Join(Directory, "paused", "tour"))
+	TryMkdir(filepath.Join(Directory, "queue"))
 }
@@ -0,0 +1,365 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dashboard implements a live full-screen terminal dashboard for
+// runs, which shows the games being played by each worker along with the
+// run's results.
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+	"laptudirm.com/x/arbiter/pkg/eve/match"
+)
+
+// Dashboard is a live terminal dashboard. A nil Dashboard ignores every
+// update, so that runs can update it without checking if it is enabled.
+type Dashboard struct {
+	mu sync.Mutex
+
+	title   string
+	started time.Time
+
+	// terminal is the terminal the dashboard is drawn on, which is the
+	// standard output the program was started with.
+	terminal *os.File
+	stdout   *os.File
+
+	// level is the logging level the program was started with.
+	level logrus.Level
+
+	workers []worker
+	table   []string
+	recent  []string
+	logs    []string
+
+	// progress is the fraction of the run which is done, with label as its
+	// description, and eta the estimated time left, if known.
+	progress float64
+	label    string
+	eta      time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// worker is the state of the game being played by a worker.
+type worker struct {
+	game    string
+	engines [2]string
+	move    match.MoveInfo
+	playing bool
+}
+
+// maxRecent is the number of recently finished games and log lines shown.
+const maxRecent = 6
+
+// Supported reports if a dashboard can be drawn, which is only possible if
+// the standard output is a terminal.
+func Supported() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Start starts a dashboard with the given title and number of workers, and
+// takes over the terminal until it is stopped. The rest of the output of
+// the program is discarded meanwhile, except for log lines, which are shown
+// on the dashboard.
+func Start(title string, workers int) *Dashboard {
+	dashboard := &Dashboard{
+		title:    title,
+		started:  time.Now(),
+		terminal: os.Stdout,
+		stdout:   os.Stdout,
+		workers:  make([]worker, workers),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if devNull, err := os.Open(os.DevNull); err == nil {
+		os.Stdout = devNull
+	}
+
+	// Games are already shown on the dashboard, so only the more important
+	// log lines are shown, unless more were asked for.
+	logrus.SetOutput(dashboard)
+	dashboard.level = logrus.GetLevel()
+	if dashboard.level == logrus.InfoLevel {
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+
+	// Switch to the alternate screen and hide the cursor.
+	fmt.Fprint(dashboard.terminal, "\x1b[?1049h\x1b[?25l")
+
+	// The terminal is restored if the run is interrupted.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			dashboard.Stop()
+			os.Exit(130)
+		case <-dashboard.done:
+			signal.Stop(interrupt)
+		}
+	}()
+
+	go dashboard.loop()
+	return dashboard
+}
+
+// Stop stops the dashboard and gives the terminal back to the program.
+func (dashboard *Dashboard) Stop() {
+	if dashboard == nil {
+		return
+	}
+
+	dashboard.mu.Lock()
+	select {
+	case <-dashboard.stop:
+		dashboard.mu.Unlock()
+		return
+	default:
+		close(dashboard.stop)
+	}
+	dashboard.mu.Unlock()
+
+	<-dashboard.done
+
+	fmt.Fprint(dashboard.terminal, "\x1b[?25h\x1b[?1049l")
+	os.Stdout = dashboard.stdout
+	logrus.SetOutput(os.Stderr)
+	logrus.SetLevel(dashboard.level)
+}
+
+// loop redraws the dashboard periodically until it is stopped.
+func (dashboard *Dashboard) loop() {
+	defer close(dashboard.done)
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-dashboard.stop:
+			return
+		case <-ticker.C:
+			dashboard.draw()
+		}
+	}
+}
+
+// StartGame shows that the worker with the given index has started the given game.
+func (dashboard *Dashboard) StartGame(index int, game string, engines [2]string) {
+	if dashboard == nil {
+		return
+	}
+
+	dashboard.mu.Lock()
+	defer dashboard.mu.Unlock()
+
+	if index < len(dashboard.workers) {
+		dashboard.workers[index] = worker{game: game, engines: engines, playing: true}
+	}
+}
+
+// Move shows a move played in the game of the worker with the given index.
+func (dashboard *Dashboard) Move(index int, move match.MoveInfo) {
+	if dashboard == nil {
+		return
+	}
+
+	dashboard.mu.Lock()
+	defer dashboard.mu.Unlock()
+
+	if index < len(dashboard.workers) {
+		dashboard.workers[index].move = move
+	}
+}
+
+// EndGame shows that the game of the worker with the given index has ended, and adds the
+// given description of its result to the recently finished games.
+func (dashboard *Dashboard) EndGame(index int, result string) {
+	if dashboard == nil {
+		return
+	}
+
+	dashboard.mu.Lock()
+	defer dashboard.mu.Unlock()
+
+	if index < len(dashboard.workers) {
+		dashboard.workers[index].playing = false
+	}
+
+	dashboard.recent = appendRecent(dashboard.recent, result)
+}
+
+// SetTable sets the lines of the run's results table.
+func (dashboard *Dashboard) SetTable(lines []string) {
+	if dashboard == nil {
+		return
+	}
+
+	dashboard.mu.Lock()
+	defer dashboard.mu.Unlock()
+
+	dashboard.table = lines
+}
+
+// SetProgress sets the progress of the run, as a fraction between 0 and 1
+// with the given description, and the estimated time left, if known.
+func (dashboard *Dashboard) SetProgress(progress float64, label string, eta time.Duration) {
+	if dashboard == nil {
+		return
+	}
+
+	dashboard.mu.Lock()
+	defer dashboard.mu.Unlock()
+
+	dashboard.progress, dashboard.label, dashboard.eta = progress, label, eta
+}
+
+// Write adds the given log output to the dashboard's log lines.
+func (dashboard *Dashboard) Write(data []byte) (int, error) {
+	dashboard.mu.Lock()
+	defer dashboard.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		dashboard.logs = appendRecent(dashboard.logs, line)
+	}
+
+	return len(data), nil
+}
+
+// appendRecent appends a line to the given lines, keeping only the last
+// maxRecent of them.
+func appendRecent(lines []string, line string) []string {
+	lines = append(lines, line)
+	if len(lines) > maxRecent {
+		lines = lines[len(lines)-maxRecent:]
+	}
+
+	return lines
+}
+
+// draw draws the dashboard on the terminal.
+func (dashboard *Dashboard) draw() {
+	dashboard.mu.Lock()
+	defer dashboard.mu.Unlock()
+
+	width, height, err := term.GetSize(int(dashboard.terminal.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	var screen []string
+	elapsed := time.Since(dashboard.started).Round(time.Second)
+	screen = append(screen, fmt.Sprintf("\x1b[1m%s\x1b[0m  elapsed %s", dashboard.title, elapsed))
+	screen = append(screen, "")
+
+	screen = append(screen, "\x1b[33mWorkers\x1b[0m")
+	for i, worker := range dashboard.workers {
+		screen = append(screen, fmt.Sprintf("%2d. %s", i+1, worker.status()))
+	}
+
+	screen = append(screen, "")
+	screen = append(screen, dashboard.table...)
+
+	screen = append(screen, "")
+	screen = append(screen, progressBar(dashboard.progress, width-40)+" "+dashboard.label)
+	if dashboard.eta > 0 {
+		screen[len(screen)-1] += fmt.Sprintf("  ETA %s", dashboard.eta.Round(time.Second))
+	}
+
+	if len(dashboard.recent) > 0 {
+		screen = append(screen, "", "\x1b[33mRecent Games\x1b[0m")
+		screen = append(screen, dashboard.recent...)
+	}
+
+	if len(dashboard.logs) > 0 {
+		screen = append(screen, "", "\x1b[33mLog\x1b[0m")
+		screen = append(screen, dashboard.logs...)
+	}
+
+	if len(screen) > height {
+		screen = screen[:height]
+	}
+
+	// Redraw from the top left corner, clearing the rest of every line.
+	var frame strings.Builder
+	frame.WriteString("\x1b[H")
+	for _, line := range screen {
+		frame.WriteString(line)
+		frame.WriteString("\x1b[K\r\n")
+	}
+
+	frame.WriteString("\x1b[J")
+	_, _ = io.WriteString(dashboard.terminal, frame.String())
+}
+
+// status returns a one line description of the worker's game.
+func (worker worker) status() string {
+	if worker.game == "" {
+		return "\x1b[2midle\x1b[0m"
+	}
+
+	status := fmt.Sprintf("%-20s %s vs %s", worker.game, worker.engines[0], worker.engines[1])
+	if !worker.playing {
+		return "\x1b[2m" + status + "\x1b[0m"
+	}
+
+	move := worker.move
+	if move.Move == "" {
+		return status + "  starting"
+	}
+
+	status += fmt.Sprintf(
+		"  ply %d %s  %s %s",
+		move.Ply, move.Move,
+		move.Clocks[0].Round(100*time.Millisecond), move.Clocks[1].Round(100*time.Millisecond),
+	)
+
+	if move.HasScore {
+		if move.Score.Mate {
+			status += fmt.Sprintf("  mate %d", move.Score.Value)
+		} else {
+			status += fmt.Sprintf("  %+d cp", move.Score.Value)
+		}
+	}
+
+	return status
+}
+
+// progressBar returns a progress bar of the given width which is filled to
+// the given fraction.
+func progressBar(progress float64, width int) string {
+	if width < 10 {
+		width = 10
+	}
+
+	if progress < 0 {
+		progress = 0
+	} else if progress > 1 {
+		progress = 1
+	}
+
+	filled := int(progress * float64(width))
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("·", width-filled) + "]"
+}
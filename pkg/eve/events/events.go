@@ -0,0 +1,180 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events implements a machine-readable stream of the events of a
+// run, which are written as newline-delimited JSON objects.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Types of the events emitted by runs.
+const (
+	GameStart = "game_start"
+	Move      = "move"
+	GameEnd   = "game_end"
+	Stats     = "stats"
+	RunEnd    = "run_end"
+)
+
+// Stream is a stream of events. A nil Stream discards every event, so that
+// runs can emit events without checking if a stream was asked for.
+type Stream struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+
+	// Moves reports if move events are emitted to the stream.
+	Moves bool
+}
+
+// Event is the envelope every event is written in.
+type Event struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data,omitempty"`
+}
+
+// Open opens a stream which writes events to the file at the given path, or
+// to the standard output if the path is "-". In the latter case, the rest of
+// the output, which is written to os.Stdout, is redirected to the standard
+// error so that it doesn't get mixed up with the events.
+func Open(path string, moves bool) (*Stream, error) {
+	if path == "-" {
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		return New(stdout, moves), nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := New(file, moves)
+	stream.closer = file
+	return stream, nil
+}
+
+// New creates a stream which writes events to the given writer.
+func New(writer io.Writer, moves bool) *Stream {
+	return &Stream{writer: writer, Moves: moves}
+}
+
+// Emit writes an event of the given type with the given data to the stream.
+// It is safe to call from multiple goroutines. Errors in writing events are
+// ignored, since they shouldn't stop the run.
+func (stream *Stream) Emit(event string, data any) {
+	if stream == nil || (event == Move && !stream.Moves) {
+		return
+	}
+
+	line, err := json.Marshal(Event{Event: event, Time: time.Now().UTC(), Data: data})
+	if err != nil {
+		return
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	_, _ = stream.writer.Write(append(line, '\n'))
+}
+
+// Close closes the stream's file, if it has one.
+func (stream *Stream) Close() error {
+	if stream == nil || stream.closer == nil {
+		return nil
+	}
+
+	return stream.closer.Close()
+}
+
+// Game identifies the game an event is about.
+type Game struct {
+	Round   int       `json:"round,omitempty"`
+	Number  int       `json:"game"`
+	Engines [2]string `json:"engines"`
+	Opening string    `json:"opening,omitempty"`
+
+	// TimeControls stores the time control of each engine, which differ in
+	// games played with time odds.
+	TimeControls [2]string `json:"tcs"`
+}
+
+// MoveData is the data of a move event.
+type MoveData struct {
+	Game   int    `json:"game"`
+	Ply    int    `json:"ply"`
+	Engine string `json:"engine"`
+	Move   string `json:"move"`
+
+	// TimeMs is the time the engine took for the move in milliseconds.
+	TimeMs int64 `json:"time_ms"`
+}
+
+// GameEndData is the data of a game end event. The result is "1-0", "0-1"
+// or "1/2-1/2", from the point of view of the first engine of the game.
+type GameEndData struct {
+	Game
+
+	White  string `json:"white"`
+	Result string `json:"result"`
+	Winner string `json:"winner,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Standing is the score of an engine in a tournament.
+type Standing struct {
+	Name   string  `json:"name"`
+	Elo    float64 `json:"elo"`
+	Error  float64 `json:"error"`
+	Wins   int     `json:"wins"`
+	Losses int     `json:"losses"`
+	Draws  int     `json:"draws"`
+}
+
+// TournamentStats is the data of a stats event of a tournament.
+type TournamentStats struct {
+	Games     int        `json:"games"`
+	Standings []Standing `json:"standings"`
+}
+
+// SPRTStats is the data of a stats event of an SPRT, from the point of view
+// of its first engine. Penta stores the number of pairs with each result,
+// from loss-loss to win-win.
+type SPRTStats struct {
+	Games  int    `json:"games"`
+	Wins   int    `json:"wins"`
+	Losses int    `json:"losses"`
+	Draws  int    `json:"draws"`
+	Penta  [5]int `json:"penta"`
+
+	Elo      float64 `json:"elo"`
+	EloError float64 `json:"elo_error"`
+
+	LLR   float64 `json:"llr"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// RunEndData is the data of a run end event. The Conclusion of an SPRT is the
+// accepted hypothesis, "H0" or "H1".
+type RunEndData struct {
+	Kind       string `json:"kind"`
+	Games      int    `json:"games"`
+	Conclusion string `json:"conclusion,omitempty"`
+}
@@ -0,0 +1,211 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpeningResults stores the results of the games played with each opening,
+// from White's point of view, so that openings which are always won by the
+// same side or are always drawn can be spotted.
+type OpeningResults struct {
+	// MinGames is the number of games an opening has to be played in before
+	// its results are judged, which is DefaultMinGames if it is zero. Results
+	// of fewer games, like a single pair of drawn games, are common even for
+	// balanced openings.
+	MinGames int
+
+	stats map[string]*OpeningStats
+	order []*OpeningStats
+}
+
+// DefaultMinGames is the default value of OpeningResults.MinGames.
+const DefaultMinGames = 6
+
+// reportedOpenings is the maximum number of lopsided openings listed by
+// OpeningResults.Report, the rest of which are only counted.
+const reportedOpenings = 10
+
+// OpeningStats stores the results of the games played with an opening.
+type OpeningStats struct {
+	Opening Opening
+
+	WhiteWins, BlackWins, Draws int
+
+	// results are the results the opening's stats belong to.
+	results *OpeningResults
+}
+
+// Games returns the number of games played with the opening.
+func (stats *OpeningStats) Games() int {
+	return stats.WhiteWins + stats.BlackWins + stats.Draws
+}
+
+// Verdict returns a description of the opening's results if they are all
+// the same across enough games, or the empty string otherwise.
+func (stats *OpeningStats) Verdict() string {
+	switch games := stats.Games(); {
+	case games < stats.results.minGames():
+		return ""
+	case stats.WhiteWins == games:
+		return "always won by white"
+	case stats.BlackWins == games:
+		return "always won by black"
+	case stats.Draws == games:
+		return "always drawn"
+	default:
+		return ""
+	}
+}
+
+// minGames returns the number of games an opening has to be played in
+// before its results are judged.
+func (results *OpeningResults) minGames() int {
+	if results.MinGames <= 0 {
+		return DefaultMinGames
+	}
+
+	return results.MinGames
+}
+
+// Add adds the result of a game played with the given opening, which is from
+// the point of view of the first engine, with white the index of the engine
+// which played White.
+func (results *OpeningResults) Add(opening Opening, result Result, white int) {
+	if results.stats == nil {
+		results.stats = map[string]*OpeningStats{}
+	}
+
+	key := opening.Position.String()
+	stats, found := results.stats[key]
+	if !found {
+		stats = &OpeningStats{Opening: opening, results: results}
+		results.stats[key] = stats
+		results.order = append(results.order, stats)
+	}
+
+	if white == 1 {
+		result = -result
+	}
+
+	switch result {
+	case Win:
+		stats.WhiteWins++
+	case Loss:
+		stats.BlackWins++
+	case Draw:
+		stats.Draws++
+	}
+}
+
+// Stats returns the results of every opening, in the order the openings
+// were first played in.
+func (results *OpeningResults) Stats() []*OpeningStats {
+	return results.order
+}
+
+// Report prints a summary of the opening results, along with the openings
+// with the most games whose results are all the same.
+func (results *OpeningResults) Report() {
+	// Openings are identified by the order in which they were first played,
+	// which is also their order in the CSV file, since the openings of pgn
+	// books and move lists share their starting position.
+	lopsided := []*OpeningStats{}
+	index := map[*OpeningStats]int{}
+	for i, stats := range results.order {
+		if stats.Verdict() != "" {
+			lopsided = append(lopsided, stats)
+			index[stats] = i + 1
+		}
+	}
+
+	// Openings with the most games first.
+	sort.SliceStable(lopsided, func(i, j int) bool {
+		return lopsided[i].Games() > lopsided[j].Games()
+	})
+
+	hidden := 0
+	if len(lopsided) > reportedOpenings {
+		hidden = len(lopsided) - reportedOpenings
+	}
+
+	fmt.Println("╔═════════════════════════════════════════════════╗")
+	fmt.Printf("%-50s║\n", fmt.Sprintf("║ OPENINGS | played %d lopsided %d", len(results.order), len(lopsided)))
+	for _, stats := range lopsided[:len(lopsided)-hidden] {
+		fmt.Printf("%-50s║\n", fmt.Sprintf(
+			"║ %-24.24s | +%d =%d -%d %s",
+			fmt.Sprintf("#%d %s", index[stats], openingLabel(stats.Opening)),
+			stats.WhiteWins, stats.Draws, stats.BlackWins, eval(stats.Opening),
+		))
+		fmt.Printf("%-50s║\n", fmt.Sprintf("║ %-24s | %s", "", stats.Verdict()))
+	}
+
+	if hidden > 0 {
+		fmt.Printf("%-50s║\n", fmt.Sprintf("║ ... and %d more lopsided openings", hidden))
+	}
+	fmt.Println("╚═════════════════════════════════════════════════╝")
+}
+
+// openingLabel returns a short description of the given opening, which is
+// its moves if it has any, and its position otherwise.
+func openingLabel(opening Opening) string {
+	if len(opening.Moves) > 0 {
+		return strings.Join(opening.Moves, " ")
+	}
+
+	return opening.FEN
+}
+
+// WriteCSV writes the results of every opening to a CSV file at the given
+// path, with the wins and losses counted from White's point of view.
+func (results *OpeningResults) WriteCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{"opening", "eval", "games", "white", "draw", "black", "verdict"})
+	for _, stats := range results.order {
+		_ = writer.Write([]string{
+			stats.Opening.Position.String(),
+			eval(stats.Opening),
+			strconv.Itoa(stats.Games()),
+			strconv.Itoa(stats.WhiteWins),
+			strconv.Itoa(stats.Draws),
+			strconv.Itoa(stats.BlackWins),
+			stats.Verdict(),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// eval returns the evaluation of the opening, if it has one.
+func eval(opening Opening) string {
+	if !opening.HasEval {
+		return ""
+	}
+
+	return fmt.Sprintf("%+d", opening.Eval)
+}
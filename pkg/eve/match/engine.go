@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,20 +34,46 @@ type EngineConfig struct {
 
 	Protocol string `yaml:"protocol"`
 
-	Stderr string `yaml:"stderr"`
+	// Stderr is the directory in which the engine's stderr is logged, with a
+	// new log file for every game. Transcript additionally logs everything
+	// sent to and received from the engine, and LogKeep is the number of the
+	// most recent log files to keep, or zero to keep all of them.
+	Stderr     string `yaml:"stderr"`
+	Transcript bool   `yaml:"transcript"`
+	LogKeep    int    `yaml:"log-keep"`
 
 	InitStr string `yaml:"init-string"`
 
+	// Referee marks a UGI engine as a referee for the games it plays. Its
+	// responses to the UGI queries are used to adjudicate those games
+	// instead of a built-in game oracle.
+	Referee bool `yaml:"referee"`
+
 	Options map[string]string `yaml:"options"`
 
 	TimeC string `yaml:"tc"`
 	Depth int    `yaml:"depth"`
 	Nodes int    `yaml:"nodes"`
+
+	// TimeMargin is the number of milliseconds by which the engine may exceed
+	// its clock without losing on time. Such overruns are still recorded.
+	TimeMargin int `yaml:"timemargin"`
+
+	// Ponder allows the engine to think on its opponent's time, which is
+	// done on the reply it expects to its move.
+	Ponder bool `yaml:"ponder"`
 }
 
-func StartEngine(config EngineConfig) (*Engine, error) {
+// StartEngine starts a new engine with the given configuration and prepares
+// it for the game with the given name, which names the first log file.
+func StartEngine(config EngineConfig, game string) (*Engine, error) {
 	var engine Engine
-	engine.protocol = config.Protocol
+
+	var err error
+	if engine.protocol, err = NewProtocol(config.Protocol); err != nil {
+		return nil, err
+	}
+
 	process := exec.Command(config.Cmd, strings.Fields(config.Arg)...)
 
 	engine.config = config
@@ -56,13 +83,30 @@ func StartEngine(config EngineConfig) (*Engine, error) {
 	stdin, _ := process.StdinPipe()
 	stdout, _ := process.StdoutPipe()
 
+	// The engine's stderr is discarded unless it is being logged.
+	engine.log = newEngineLog(config)
+	if err := engine.log.Open(game); err != nil {
+		return nil, err
+	}
+
+	if engine.log != nil {
+		stderr, _ := process.StderrPipe()
+		go func() {
+			scanner := bufio.NewScanner(stderr)
+			for scanner.Scan() {
+				engine.log.Stderr(scanner.Text())
+			}
+		}()
+	}
+
 	engine.writer = bufio.NewWriter(stdin)
 	engine.reader = bufio.NewReader(stdout)
-	engine.lines = make(chan string)
+	engine.lines = make(chan engineLine)
 
 	engine.Cmd = process
 
 	if err := engine.Cmd.Start(); err != nil {
+		engine.log.Close()
 		return nil, err
 	}
 
@@ -75,28 +119,39 @@ func StartEngine(config EngineConfig) (*Engine, error) {
 				return
 			}
 
+			// Note the time as soon as the line is read, so that the time taken
+			// by arbiter to process it isn't charged to the engine.
+			received := time.Now()
 			line = strings.Trim(line, " \n\t\r")
 
 			logrus.Debugf("info: ("+engine.config.Name+")> %s\n", line)
-			engine.lines <- line
+			engine.log.Received(line)
+			engine.lines <- engineLine{text: line, received: received}
 		}
 	}()
 
+	if err := engine.start(); err != nil {
+		// Don't leave a half initialized engine running.
+		_ = engine.Kill()
+		return nil, err
+	}
+
+	return &engine, nil
+}
+
+// start initializes a newly started engine and prepares it for a game.
+func (engine *Engine) start() error {
 	if engine.config.InitStr != "" {
 		if err := engine.Write(engine.config.InitStr); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	if err := engine.Initialize(); err != nil {
-		return nil, err
-	}
-
-	if err := engine.NewGame(); err != nil {
-		return nil, err
+		return err
 	}
 
-	return &engine, nil
+	return engine.NewGame()
 }
 
 type Engine struct {
@@ -104,57 +159,244 @@ type Engine struct {
 
 	*exec.Cmd
 
-	protocol string
+	protocol Protocol
 
 	writer *bufio.Writer
 	reader *bufio.Reader
 
-	lines chan string
+	lines chan engineLine
+
+	log *engineLog
+
+	// written is the time at which the last command was written to the engine
+	// and received the time at which the last line returned by Await was read
+	// from it, which are used to time the engine's searches.
+	written, received time.Time
+
+	// started is the time at which the engine was told to start searching.
+	started time.Time
+
+	// searching is set while the engine is searching a position. Engines
+	// which are stopped in the middle of a search, like when they lose on
+	// time, can't be reused since they may still send their bestmove.
+	searching bool
+
+	// pondering is set while the engine is pondering on ponderMove, which is
+	// the reply to its last move which the engine expects.
+	pondering  bool
+	ponderMove string
+
+	// info is the last line with a score sent by the engine while searching.
+	info string
+
+	// id is the name the engine reported for itself, which usually includes
+	// its version.
+	id string
 
 	err error
 }
 
-// NewGame prepares the engine for a new game of chess.
+// NewGame prepares the engine for a new game.
 func (engine *Engine) NewGame() error {
-	if err := engine.Write(engine.protocol + "newgame"); err != nil {
+	return engine.protocol.NewGame(engine)
+}
+
+// Initialize initializes the engine on startup.
+func (engine *Engine) Initialize() error {
+	return engine.protocol.Initialize(engine)
+}
+
+// Synchronize waits for the engine to complete some time consuming task
+// and synchronizes the interface with it.
+func (engine *Engine) Synchronize() error {
+	return engine.protocol.Synchronize(engine)
+}
+
+// Position sets up the given position on the engine.
+func (engine *Engine) Position(position Position) error {
+	return engine.protocol.Position(engine, position)
+}
+
+// Go makes the engine start searching the current position.
+func (engine *Engine) Go(clock Clock) error {
+	engine.searching = true
+	engine.info = ""
+	if err := engine.protocol.Go(engine, clock); err != nil {
 		return err
 	}
 
-	return engine.Synchronize()
+	// The search starts when the last command, the one telling the engine
+	// to go, has been written.
+	engine.started = engine.written
+	return nil
 }
 
-// Initialize initializes the engine on startup.
-func (engine *Engine) Initialize() error {
-	if err := engine.Write(engine.protocol); err != nil {
+// Ponder makes the engine start pondering on the given position, which is
+// reached by playing its expected reply to its last move.
+func (engine *Engine) Ponder(position Position, clock Clock) error {
+	engine.searching = true
+	engine.pondering = true
+	return engine.protocol.Ponder(engine, position, clock)
+}
+
+// PonderHit tells the pondering engine that its expected reply was played.
+// The engine's search, and its clock, start when it is told so.
+func (engine *Engine) PonderHit() error {
+	engine.pondering = false
+	if err := engine.protocol.PonderHit(engine); err != nil {
 		return err
 	}
 
-	_, err := engine.Await(engine.protocol+"ok", 5*time.Second)
-	return err
+	engine.started = engine.written
+	return nil
 }
 
-// Synchronize waits for the engine to complete some time consuming task
-// and synchronizes the interface with it.
-func (engine *Engine) Synchronize() error {
-	if err := engine.Write("isready"); err != nil {
+// StopPonder stops the engine from pondering after an unexpected reply was
+// played, and discards the move it sends.
+func (engine *Engine) StopPonder() error {
+	engine.pondering = false
+	if err := engine.protocol.Stop(engine); err != nil {
 		return err
 	}
 
-	_, err := engine.Await("readyok", 5*time.Second)
+	_, err := engine.protocol.BestMove(engine, 5*time.Second)
+	engine.ponderMove = ""
+	if errors.Is(err, ErrReadTimeout) {
+		return err
+	}
+
+	engine.searching = false
+	if errors.Is(err, ErrResigned) || errors.Is(err, ErrFalseClaim) {
+		// The engine's moves on the pondered position are irrelevant.
+		return nil
+	}
+
 	return err
 }
 
-// Kill kills the engine.
+// GameOver tells the engine that the game has ended with the given result,
+// from its own point of view.
+func (engine *Engine) GameOver(result Result) error {
+	return engine.protocol.GameOver(engine, result)
+}
+
+// Pondering checks if the engine is pondering.
+func (engine *Engine) Pondering() bool {
+	return engine.pondering
+}
+
+// PonderMove returns the reply to its last move which the engine expects,
+// or the empty string if it didn't report one.
+func (engine *Engine) PonderMove() string {
+	return engine.ponderMove
+}
+
+// BestMove waits for the engine to finish searching and returns its move
+// along with the time taken by the search, which is measured from the time
+// the engine was told to go to the time its move was received. The timeout
+// is measured from the same point.
+func (engine *Engine) BestMove(timeout time.Duration) (string, time.Duration, error) {
+	engine.ponderMove = ""
+	move, err := engine.protocol.BestMove(engine, timeout-time.Since(engine.started))
+	if !errors.Is(err, ErrReadTimeout) {
+		engine.searching = false
+	}
+
+	return move, engine.received.Sub(engine.started), err
+}
+
+// Reusable checks if the engine can be used for another game, which isn't
+// the case if it has crashed or was stopped in the middle of a search.
+func (engine *Engine) Reusable() bool {
+	return engine.err == nil && !engine.searching
+}
+
+// OpenLog makes the engine log its diagnostics for the game with the given
+// name, if logging is enabled for it.
+func (engine *Engine) OpenLog(game string) error {
+	return engine.log.Open(game)
+}
+
+// Kill kills the engine. Engines which don't quit on their own within a
+// few seconds of being asked to are killed forcefully.
 func (engine *Engine) Kill() error {
-	if err := engine.Write("quit"); err != nil {
+	defer engine.log.Close()
+
+	// Crashed engines can't be asked to quit.
+	_ = engine.protocol.Quit(engine)
+
+	done := make(chan error, 1)
+	go func() { done <- engine.Wait() }()
+
+	select {
+	case err := <-done:
 		return err
+	case <-time.After(5 * time.Second):
+		_ = engine.Process.Kill()
+		return <-done
+	}
+}
+
+// Query asks the engine the given UGI query and returns its response.
+func (engine *Engine) Query(query string) (string, error) {
+	if err := engine.Write("query %s", query); err != nil {
+		return "", err
+	}
+
+	line, err := engine.Await("^response ", 5*time.Second)
+	if err != nil {
+		return "", err
 	}
 
-	return engine.Wait()
+	return strings.TrimSpace(strings.TrimPrefix(line, "response ")), nil
 }
 
 var ErrReadTimeout = errors.New("engine: read i/o timeout")
 
+var ErrNoScore = errors.New("engine: no score reported")
+
+// Score is an engine's evaluation of a position from the side to move's
+// perspective, either in centipawns or as the number of moves to mate.
+type Score struct {
+	Value int
+	Mate  bool
+}
+
+// Score returns the score of the engine's last search, as reported in its
+// info lines. Only the UXI protocols report scores like that.
+func (engine *Engine) Score() (Score, error) {
+	fields := strings.Fields(engine.info)
+	for i := 0; i+2 < len(fields); i++ {
+		if fields[i] != "score" {
+			continue
+		}
+
+		value, err := strconv.Atoi(fields[i+2])
+		if err != nil {
+			return Score{}, err
+		}
+
+		switch fields[i+1] {
+		case "cp":
+			return Score{Value: value}, nil
+		case "mate":
+			return Score{Value: value, Mate: true}, nil
+		}
+	}
+
+	return Score{}, ErrNoScore
+}
+
+// ID returns the name the engine reported for itself, or the name in its
+// configuration if it didn't report one.
+func (engine *Engine) ID() string {
+	if engine.id == "" {
+		return engine.config.Name
+	}
+
+	return engine.id
+}
+
 // Await is a utility function which waits for a particular string from
 // the engine with a fixed timeout.
 func (engine *Engine) Await(pattern string, timeout time.Duration) (string, error) {
@@ -172,21 +414,69 @@ func (engine *Engine) Await(pattern string, timeout time.Duration) (string, erro
 
 			return "", ErrReadTimeout
 
-		case line := <-engine.lines:
-			if regex.MatchString(line) {
+		case line, ok := <-engine.lines:
+			if !ok {
+				// engine stopped: the reader has recorded why
+				return "", engine.err
+			}
+
+			if regex.MatchString(line.text) {
 				// line is the expected line
-				return line, nil
+				engine.received = line.received
+				return line.text, nil
+			}
+
+			if strings.HasPrefix(line.text, "info ") && strings.Contains(line.text, " score ") {
+				engine.info = line.text
+			}
+
+			if strings.HasPrefix(line.text, "id name ") {
+				engine.id = strings.TrimPrefix(line.text, "id name ")
 			}
 		}
 	}
 }
 
+// Drain discards the lines sent by the engine until it has been silent for
+// the given duration, for protocols which have no way to synchronize with it.
+func (engine *Engine) Drain(quiet time.Duration) error {
+	timer := time.NewTimer(quiet)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return nil
+
+		case _, ok := <-engine.lines:
+			if !ok {
+				// engine stopped: the reader has recorded why
+				return engine.err
+			}
+
+			timer.Reset(quiet)
+		}
+	}
+}
+
 func (engine *Engine) Write(format string, a ...any) error {
 	logrus.Debugf("info: ("+engine.config.Name+")< "+format+"\n", a...)
+	engine.log.Sent(fmt.Sprintf(format, a...))
 
 	if _, err := fmt.Fprintf(engine.writer, format+"\n", a...); err != nil {
 		return err
 	}
 
-	return engine.writer.Flush()
+	if err := engine.writer.Flush(); err != nil {
+		return err
+	}
+
+	engine.written = time.Now()
+	return nil
+}
+
+// engineLine represents a single line of output from an engine.
+type engineLine struct {
+	text     string
+	received time.Time
 }
@@ -14,19 +14,86 @@
 package match
 
 import (
-	"strings"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
 	"laptudirm.com/x/arbiter/pkg/eve/match/games"
 )
 
 type Config struct {
 	Game, PositionFEN string
 
+	// OpeningMoves are the moves of the game's opening, which are played from
+	// the starting position before the engines start playing.
+	OpeningMoves []string
+
+	// Name is a unique name for the game, which is used to name the log
+	// files of the engines playing it, suffixed with .p1 or .p2.
+	Name string
+
 	Engines [2]EngineConfig
+
+	// OnMove is called with every move played by the engines, if it is set.
+	OnMove func(move MoveInfo) `json:"-"`
 }
 
-func Run(config *Config) (Result, string) {
+// MoveInfo stores the details of a move played in a game.
+type MoveInfo struct {
+	// Engine is the index of the engine which played the move, Ply is the
+	// number of moves played in the game so far, including this one.
+	Engine int
+	Ply    int
+	Move   string
+
+	// Elapsed is the time the engine took for the move, and Clocks stores
+	// the time left on the clocks of the engines after it.
+	Elapsed time.Duration
+	Clocks  [2]time.Duration
+
+	// Score is the engine's score for the move, if HasScore is set.
+	Score    Score
+	HasScore bool
+}
+
+// Outcome stores the outcome of a game played by Run.
+type Outcome struct {
+	Result Result
+	Reason string
+
+	// Engines stores the names the engines reported for themselves, and
+	// Moves the moves played in the game after its opening.
+	Engines [2]string
+	Moves   []string
+
+	// White is the index of the engine which played White in the game. The
+	// result is from the point of view of the first engine regardless.
+	White int
+
+	// Time stores the time usage statistics of each engine in the game.
+	Time [2]TimeUsage
+}
+
+// Run plays a game with the given configuration and returns its outcome.
+// The engines are taken from and returned to the given Pool, and if it is
+// nil, new engines are started for the game.
+func Run(config *Config, pool *Pool) Outcome {
+	var outcome Outcome
+	outcome.Result, outcome.Reason = play(config, pool, &outcome)
+	return outcome
+}
+
+// play plays a game with the given configuration and returns its result and
+// the reason for it. Other details of the game are recorded in the Outcome.
+func play(config *Config, pool *Pool, outcome *Outcome) (result Result, reason string) {
+	if pool == nil {
+		pool = NewPool(0)
+		defer pool.Close()
+	}
+
 	engines := [2]*Engine{}
 	remaining_time := [2]TimeControl{}
 
@@ -40,85 +107,254 @@ func Run(config *Config) (Result, string) {
 		return Win, err.Error()
 	}
 
-	if engines[0], err = StartEngine(config.Engines[0]); err != nil {
+	// The time controls are kept to refill the clocks of cyclic ones.
+	controls := remaining_time
+
+	if engines[0], err = pool.Get(config.Engines[0], config.Name+".p1"); err != nil {
 		return Loss, err.Error()
 	}
 
-	if engines[1], err = StartEngine(config.Engines[1]); err != nil {
+	defer pool.Put(engines[0])
+
+	if engines[1], err = pool.Get(config.Engines[1], config.Name+".p2"); err != nil {
 		return Win, err.Error()
 	}
 
-	defer engines[0].Kill()
-	defer engines[1].Kill()
+	defer pool.Put(engines[1])
+
+	outcome.Engines = [2]string{engines[0].ID(), engines[1].ID()}
+
+	// The engines which can be reused are told the result of the game, from
+	// their own point of view, after they have stopped pondering.
+	defer func() {
+		for i, engine := range engines {
+			if engine.Reusable() {
+				_ = engine.GameOver(result * Result(1-2*i))
+			}
+		}
+	}()
+
+	// Engines which are still pondering when the game ends are stopped before
+	// they are returned to the pool, so that they can be reused.
+	defer func() {
+		for _, engine := range engines {
+			if engine.Pondering() {
+				_ = engine.StopPonder()
+			}
+		}
+	}()
+
+	// Games are adjudicated by the referee engines, if any, and otherwise by
+	// the built-in oracle for the game being played.
+	var oracle games.Oracle
+	referee := NewReferee(engines)
+	if referee != nil {
+		oracle = referee
+	} else if oracle = games.GetOracle(config.Game); oracle == nil {
+		return Draw, fmt.Sprintf("no oracle or referee for game %s", config.Game)
+	}
+
+	oracle.Initialize(config.PositionFEN)
+
+	// The position is sent to the engines from the last zeroing move, and
+	// the config is left as it is, since it is used to record the game.
+	fen := config.PositionFEN
 
-	oracle := games.GetOracle(config.Game)
-	if oracle != nil {
-		oracle.Initialize(config.PositionFEN)
+	// The opening's moves are sent to the engines with the rest of the game.
+	moves := slices.Clone(config.OpeningMoves)
+	for _, move := range moves {
+		if err := oracle.MakeMove(move); err != nil {
+			return Draw, fmt.Sprintf("Illegal Opening Move %s", move)
+		}
 	}
 
-	moves := ""
-	// : EngineIndex
-	whiteEngine := uint8(oracle.SideToMove())
+	// The first engine plays the side to move after the opening. The clocks
+	// are kept by engine, and are mapped to the colors by whiteEngine, the
+	// index of the engine playing white.
+	whiteEngine := 0
+	if oracle.SideToMove() != games.White {
+		whiteEngine = 1
+	}
+
+	outcome.White = whiteEngine
 	engineToMove := 0
+	if referee != nil && referee.Err != nil {
+		return refereeFault(referee)
+	}
+
+	// clockOf returns the clock of the game with the given engine to move.
+	clockOf := func(engine int) Clock {
+		clock := Clock{
+			White: remaining_time[whiteEngine],
+			Black: remaining_time[whiteEngine^1],
+			Turn:  games.White,
+		}
+
+		if engine != whiteEngine {
+			clock.Turn = games.Black
+		}
+
+		return clock
+	}
+
+	lastMove := ""
 	for {
 		engine := engines[engineToMove]
 
-		if err := engine.Write("position fen %s moves%s", config.PositionFEN, moves); err != nil {
-			return GameLostBy[engineToMove], err.Error()
+		// An engine which pondered on the move which was played continues its
+		// search, and any other pondering engine is stopped.
+		ponderHit := false
+		if engine.Pondering() {
+			if engine.PonderMove() == lastMove {
+				ponderHit = true
+			} else if err := engine.StopPonder(); err != nil {
+				return GameLostBy[engineToMove], err.Error()
+			}
 		}
 
-		if err := engine.Synchronize(); err != nil {
-			return GameLostBy[engineToMove], err.Error()
+		if ponderHit {
+			if err := engine.PonderHit(); err != nil {
+				return GameLostBy[engineToMove], err.Error()
+			}
+		} else {
+			position := Position{FEN: fen, Moves: moves}
+			if err := engine.Position(position); err != nil {
+				return GameLostBy[engineToMove], err.Error()
+			}
+
+			if err := engine.Synchronize(); err != nil {
+				return GameLostBy[engineToMove], err.Error()
+			}
+
+			if err := engine.Go(clockOf(engineToMove)); err != nil {
+				return GameLostBy[engineToMove], err.Error()
+			}
+		}
+
+		remaining := &remaining_time[engineToMove]
+		limit := remaining.Base + remaining.Byoyomi
+		if remaining.MoveTime > 0 {
+			limit = remaining.MoveTime
+		}
+
+		// The engine only forfeits on time if it exceeds its clock by more
+		// than its time margin, but smaller overruns are still recorded.
+		margin := time.Duration(engine.config.TimeMargin) * time.Millisecond
+		bestmove, timeSpent, err := engine.BestMove(limit + margin)
+		if errors.Is(err, ErrReadTimeout) {
+			outcome.Time[engineToMove].Forfeits++
+			return GameLostBy[engineToMove], "Time Forfeit"
+		}
+
+		if err == nil {
+			outcome.Time[engineToMove].Record(timeSpent)
+			if timeSpent > limit {
+				outcome.Time[engineToMove].Overruns++
+				logrus.Warnf(
+					"%s exceeded its time by %s, within its margin",
+					engine.config.Name, (timeSpent - limit).Round(time.Millisecond),
+				)
+			}
+		}
+
+		if remaining.MoveTime == 0 {
+			// Time spent from the byoyomi period or the time margin isn't
+			// taken from the clock.
+			remaining.Base -= timeSpent
+			if remaining.Base < 0 {
+				remaining.Base = 0
+			}
+
+			remaining.Base += remaining.Inc
+
+			// The base time is added to the clock again once the engine has
+			// played all the moves of a period of a cyclic time control.
+			if control := controls[engineToMove]; control.MovesToGo > 0 {
+				remaining.MovesToGo--
+				if remaining.MovesToGo <= 0 {
+					remaining.Base += control.Base
+					remaining.MovesToGo = control.MovesToGo
+				}
+			}
 		}
 
-		if err := engine.Write(
-			"go wtime %d btime %d winc %d binc %d",
-			remaining_time[whiteEngine].Base.Milliseconds(),
-			remaining_time[1^whiteEngine].Base.Milliseconds(),
-			remaining_time[whiteEngine].Inc.Milliseconds(),
-			remaining_time[1^whiteEngine].Inc.Milliseconds(),
-		); err != nil {
+		switch {
+		case errors.Is(err, ErrResigned):
+			return GameLostBy[engineToMove], "Resignation"
+		case errors.Is(err, ErrFalseClaim):
+			return GameLostBy[engineToMove], "False Claim"
+		case err != nil:
 			return GameLostBy[engineToMove], err.Error()
 		}
 
-		startTime := time.Now()
-		line, err := engine.Await(
-			"bestmove .*",
-			remaining_time[engineToMove].Base,
-		)
-		timeSpent := time.Since(startTime)
-		remaining_time[engineToMove].Base -= timeSpent
-		remaining_time[engineToMove].Base += remaining_time[engineToMove].Inc
+		moves = append(moves, bestmove)
+		outcome.Moves = append(outcome.Moves, bestmove)
+		lastMove = bestmove
 
-		if err != nil {
+		if err := oracle.MakeMove(bestmove); err != nil {
 			return GameLostBy[engineToMove], err.Error()
 		}
 
-		bestmove := strings.Fields(line)[1]
-		moves += " " + bestmove
+		if config.OnMove != nil {
+			info := MoveInfo{
+				Engine:  engineToMove,
+				Ply:     len(outcome.Moves),
+				Move:    bestmove,
+				Elapsed: timeSpent,
+				Clocks:  [2]time.Duration{remaining_time[0].Base, remaining_time[1].Base},
+			}
+
+			if score, err := engine.Score(); err == nil {
+				info.Score, info.HasScore = score, true
+			}
+
+			config.OnMove(info)
+		}
 
 		engineToMove ^= 1
 
-		if oracle != nil {
-			err := oracle.MakeMove(bestmove)
-			if err != nil {
-				return GameLostBy[engineToMove], err.Error()
-			}
+		result, reason := oracle.GameResult()
+		if referee != nil && referee.Err != nil {
+			return refereeFault(referee)
+		}
+
+		switch result {
+		case games.StmWins:
+			return Win - Result(2*engineToMove), reason
+		case games.XtmWins:
+			return Loss + Result(2*engineToMove), reason
+		case games.Draw:
+			return Draw, reason
+		}
+
+		if oracle.ZeroMoves() {
+			fen = oracle.FEN()
+			moves = []string{}
+		}
 
-			result, reason := oracle.GameResult()
-			switch result {
-			case games.StmWins:
-				return Win - Result(2*engineToMove), reason
-			case games.XtmWins:
-				return Loss + Result(2*engineToMove), reason
-			case games.Draw:
-				return Draw, reason
+		// The engine which just moved ponders on the reply it expects during
+		// its opponent's turn, with its clock as it is now.
+		ponderer := engines[engineToMove^1]
+		if ponderer.config.Ponder && ponderer.PonderMove() != "" {
+			position := Position{
+				FEN:   fen,
+				Moves: append(slices.Clone(moves), ponderer.PonderMove()),
 			}
 
-			if oracle.ZeroMoves() {
-				config.PositionFEN = oracle.FEN()
-				moves = ""
+			if err := ponderer.Ponder(position, clockOf(engineToMove^1)); err != nil {
+				return GameLostBy[engineToMove^1], err.Error()
 			}
 		}
 	}
 }
+
+// refereeFault returns the result of a game which had to be stopped because
+// of a fault in one of its referees. Disagreements between the referees
+// can't be blamed on any single engine, so the game is drawn.
+func refereeFault(referee *Referee) (Result, string) {
+	if referee.Culprit < 0 {
+		return Draw, "Referee Disagreement"
+	}
+
+	return GameLostBy[referee.Culprit], referee.Err.Error()
+}
@@ -8,6 +8,9 @@ import (
 	"strings"
 )
 
+// AtaxxStartpos is the FEN of the standard starting position of Ataxx.
+const AtaxxStartpos = "x5o/7/7/7/7/7/o5x x 0 1"
+
 type AtaxxOracle struct {
 	position Position
 }
@@ -30,6 +33,59 @@ func (oracle *AtaxxOracle) MakeMove(movstr string) error {
 	return nil
 }
 
+func (oracle *AtaxxOracle) Validate(fenstr string) (string, error) {
+	fields := strings.Fields(fenstr)
+	if len(fields) < 2 {
+		return "", errors.New("fen has less than 2 fields")
+	}
+
+	// The move counters are optional, and anything after them is ignored.
+	fields = fields[:2+counters(fields, 2, 2)]
+	for i := 2; i < len(fields); i++ {
+		fields[i] = strings.TrimSuffix(fields[i], ";")
+	}
+
+	if err := validateBoard(fields[0], 7, 7, "xo-", false); err != nil {
+		return "", err
+	}
+
+	if fields[1] != "x" && fields[1] != "o" {
+		return "", fmt.Errorf("invalid side to move %s", fields[1])
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+func (oracle *AtaxxOracle) LegalMoves() []string {
+	position := &oracle.position
+	empty := all ^ position.pieces[0].Data ^ position.pieces[1].Data ^ position.gaps.Data
+
+	var moves []string
+
+	// Singles are denoted by their destination square only.
+	singles := position.Us().Singles().Data & empty
+	for ; singles != 0; singles &= singles - 1 {
+		to := Square{uint8(bits.TrailingZeros64(singles))}
+		moves = append(moves, Move{to, to}.String())
+	}
+
+	for pieces := position.Us().Data; pieces != 0; pieces &= pieces - 1 {
+		from := Square{uint8(bits.TrailingZeros64(pieces))}
+		doubles := Bitboard{1 << from.Data}.Doubles().Data & empty
+		for ; doubles != 0; doubles &= doubles - 1 {
+			to := Square{uint8(bits.TrailingZeros64(doubles))}
+			moves = append(moves, Move{from, to}.String())
+		}
+	}
+
+	// A side with no moves has to pass.
+	if len(moves) == 0 {
+		moves = append(moves, NULLMOVE.String())
+	}
+
+	return moves
+}
+
 func (oracle *AtaxxOracle) FEN() string {
 	return oracle.position.GetFen()
 }
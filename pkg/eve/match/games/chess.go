@@ -2,6 +2,8 @@ package games
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 
 	"laptudirm.com/x/mess/pkg/board"
@@ -9,6 +11,9 @@ import (
 	"laptudirm.com/x/mess/pkg/formats/fen"
 )
 
+// ChessStartpos is the FEN of the standard starting position of Chess.
+const ChessStartpos = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
 type ChessOracle struct {
 	board *board.Board
 	moves []move.Move
@@ -20,7 +25,7 @@ func (oracle *ChessOracle) Initialize(fenstr string) {
 }
 
 func (oracle *ChessOracle) SideToMove() Color {
-	return Color(oracle.SideToMove())
+	return Color(oracle.board.SideToMove)
 }
 
 func (oracle *ChessOracle) MakeMove(mov_str string) error {
@@ -42,6 +47,145 @@ func (oracle *ChessOracle) MakeMove(mov_str string) error {
 	return nil
 }
 
+// ParseSAN converts the given move in Standard Algebraic Notation into the
+// UCI notation used by the engines, if it is legal in the current position.
+func (oracle *ChessOracle) ParseSAN(san string) (string, error) {
+	str := strings.TrimRight(san, "+#!?")
+	squares := chessSquares(strings.Fields(oracle.FEN())[0])
+
+	// Castling is converted to the king's move to its destination square.
+	if castling := strings.ReplaceAll(str, "0", "O"); castling == "O-O" || castling == "O-O-O" {
+		for _, mov := range oracle.moves {
+			uci := mov.String()
+			from, to := chessIndex(uci[0:2]), chessIndex(uci[2:4])
+			if lower(squares[from]) == 'k' &&
+				((castling == "O-O" && to == from+2) || (castling == "O-O-O" && to == from-2)) {
+				return uci, nil
+			}
+		}
+
+		return "", fmt.Errorf("illegal move %s", san)
+	}
+
+	// The promotion piece is the last character, with an optional =.
+	promotion := ""
+	if n := len(str); n > 0 && strings.ContainsRune("NBRQ", rune(str[n-1])) {
+		promotion = strings.ToLower(str[n-1:])
+		str = strings.TrimSuffix(str[:n-1], "=")
+	}
+
+	if len(str) < 2 {
+		return "", fmt.Errorf("invalid move %s", san)
+	}
+
+	piece := byte('p')
+	if strings.ContainsRune("NBRQK", rune(str[0])) {
+		piece = lower(str[0])
+		str = str[1:]
+	}
+
+	// The destination square is preceded by the optional disambiguation of
+	// the piece's origin square, and the capture marker.
+	target := str[len(str)-2:]
+	disambiguation := strings.ReplaceAll(str[:len(str)-2], "x", "")
+
+	found := ""
+	for _, mov := range oracle.moves {
+		uci := mov.String()
+		if uci[2:4] != target || uci[4:] != promotion ||
+			lower(squares[chessIndex(uci[0:2])]) != piece {
+			continue
+		}
+
+		matches := true
+		for _, char := range disambiguation {
+			if !strings.ContainsRune(uci[0:2], char) {
+				matches = false
+			}
+		}
+
+		if !matches {
+			continue
+		}
+
+		if found != "" {
+			return "", fmt.Errorf("ambiguous move %s", san)
+		}
+
+		found = uci
+	}
+
+	if found == "" {
+		return "", fmt.Errorf("illegal move %s", san)
+	}
+
+	return found, nil
+}
+
+// castlingRegexp matches the castling rights field of a FEN, which may also
+// be in the Shredder-FEN or X-FEN formats used for Chess960.
+var castlingRegexp = regexp.MustCompile(`^(-|[KQA-H]{0,2}[kqa-h]{0,2})$`)
+
+func (oracle *ChessOracle) Validate(fenstr string) (string, error) {
+	fields := strings.Fields(fenstr)
+	if len(fields) < 4 {
+		return "", errors.New("fen has less than 4 fields")
+	}
+
+	// The move counters are optional, and anything after the first four
+	// fields which isn't a move counter is an EPD operation.
+	fields = fields[:4+counters(fields, 4, 2)]
+	for i := 4; i < len(fields); i++ {
+		fields[i] = strings.TrimSuffix(fields[i], ";")
+	}
+
+	if err := validateBoard(fields[0], 8, 8, "pnbrqkPNBRQK", false); err != nil {
+		return "", err
+	}
+
+	squares := chessSquares(fields[0])
+	kings := [2]int{}
+	for square, piece := range squares {
+		switch piece {
+		case 'K':
+			kings[0]++
+		case 'k':
+			kings[1]++
+		case 'P', 'p':
+			if square < 8 || square >= 56 {
+				return "", fmt.Errorf("pawn on the back rank %s", chessSquare(square))
+			}
+		}
+	}
+
+	if kings != [2]int{1, 1} {
+		return "", errors.New("each side must have exactly one king")
+	}
+
+	if fields[1] != "w" && fields[1] != "b" {
+		return "", fmt.Errorf("invalid side to move %s", fields[1])
+	}
+
+	if fields[2] == "" || !castlingRegexp.MatchString(fields[2]) {
+		return "", fmt.Errorf("invalid castling rights %s", fields[2])
+	}
+
+	if ep := fields[3]; ep != "-" && (len(ep) != 2 || ep[0] < 'a' || ep[0] > 'h' || (ep[1] != '3' && ep[1] != '6')) {
+		return "", fmt.Errorf("invalid en passant square %s", ep)
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+func (oracle *ChessOracle) LegalMoves() []string {
+	moves := make([]string, len(oracle.moves))
+	for i, mov := range oracle.moves {
+		moves[i] = mov.String()
+	}
+
+	return moves
+}
+
 func (oracle *ChessOracle) FEN() string {
 	fen := [6]string(oracle.board.FEN())
 	return strings.Join(fen[:], " ")
@@ -70,3 +214,46 @@ func (oracle *ChessOracle) GameResult() (Result, string) {
 
 	return Ongoing, ""
 }
+
+// chessSquares parses the piece placement field of a FEN into an array of
+// squares indexed from a1 to h8, with empty squares set to zero.
+func chessSquares(placement string) [64]byte {
+	var squares [64]byte
+
+	rank, file := 7, 0
+	for i := 0; i < len(placement); i++ {
+		switch char := placement[i]; {
+		case char == '/':
+			rank, file = rank-1, 0
+		case char >= '1' && char <= '8':
+			file += int(char - '0')
+		default:
+			if rank >= 0 && file < 8 {
+				squares[8*rank+file] = char
+			}
+
+			file++
+		}
+	}
+
+	return squares
+}
+
+// chessIndex returns the index of the square with the given name.
+func chessIndex(square string) int {
+	return 8*int(square[1]-'1') + int(square[0]-'a')
+}
+
+// chessSquare returns the name of the square with the given index.
+func chessSquare(square int) string {
+	return string([]byte{'a' + byte(square%8), '1' + byte(square/8)})
+}
+
+// lower returns the lowercase version of the given ascii letter.
+func lower(char byte) byte {
+	if char >= 'A' && char <= 'Z' {
+		return char + 'a' - 'A'
+	}
+
+	return char
+}
@@ -0,0 +1,76 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package games
+
+import "testing"
+
+func TestChessParseSAN(t *testing.T) {
+	tests := []struct {
+		fen string
+		san string
+		uci string // empty if the move is illegal or ambiguous
+	}{
+		{ChessStartpos, "e4", "e2e4"},
+		{ChessStartpos, "Nf3", "g1f3"},
+		{ChessStartpos, "Nc3!?", "b1c3"},
+		{ChessStartpos, "e5", ""},
+		{ChessStartpos, "Ke2", ""},
+		{ChessStartpos, "O-O", ""},
+
+		// Castling, which is converted to the king's move.
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O-O", "e1c1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O-O", "e8c8"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w kq - 0 1", "O-O", ""},
+
+		// Disambiguation by file and by rank.
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rd1", ""},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rad1", "a1d1"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rhd1", "h1d1"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rh2", "h1h2"},
+		{"4k3/8/8/8/R7/8/4K3/R7 w - - 0 1", "Ra3", ""},
+		{"4k3/8/8/8/R7/8/4K3/R7 w - - 0 1", "R1a3", "a1a3"},
+		{"4k3/8/8/8/R7/8/4K3/R7 w - - 0 1", "R4a3", "a4a3"},
+		{"4k3/8/8/8/8/8/4K3/1N3N2 w - - 0 1", "Nd2", ""},
+		{"4k3/8/8/8/8/8/4K3/1N3N2 w - - 0 1", "Nbd2", "b1d2"},
+		{"4k3/8/8/8/8/8/4K3/1N3N2 w - - 0 1", "Nfd2", "f1d2"},
+
+		// Captures, including en passant, and checks.
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "exd5", "e4d5"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "Bb5+", "f1b5"},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", "exf6", "e5f6"},
+
+		// Promotions, with and without the =.
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8=Q", "a7a8q"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8N", "a7a8n"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8", ""},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "axb8=R+", "a7b8r"},
+	}
+
+	for _, test := range tests {
+		oracle := &ChessOracle{}
+		oracle.Initialize(test.fen)
+
+		uci, err := oracle.ParseSAN(test.san)
+		switch {
+		case test.uci == "" && err == nil:
+			t.Errorf("%s in %s: got %s, want an error", test.san, test.fen, uci)
+		case test.uci != "" && err != nil:
+			t.Errorf("%s in %s: %v", test.san, test.fen, err)
+		case uci != test.uci:
+			t.Errorf("%s in %s: got %s, want %s", test.san, test.fen, uci, test.uci)
+		}
+	}
+}
@@ -1,11 +1,19 @@
 package games
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 func GetOracle(name string) Oracle {
 	switch name {
 	case "ataxx":
 		return &AtaxxOracle{}
 	case "chess":
 		return &ChessOracle{}
+	case "shogi":
+		return &ShogiOracle{}
 	default:
 		return nil
 	}
@@ -20,6 +28,35 @@ type Oracle interface {
 	ZeroMoves() bool
 }
 
+// Startpos returns the standard starting position of the given game, or the
+// empty string if the game isn't supported.
+func Startpos(game string) string {
+	switch game {
+	case "ataxx":
+		return AtaxxStartpos
+	case "chess":
+		return ChessStartpos
+	case "shogi":
+		return ShogiStartpos
+	default:
+		return ""
+	}
+}
+
+// MoveGenerator is implemented by oracles which can list the legal moves in
+// the current position.
+type MoveGenerator interface {
+	LegalMoves() []string
+}
+
+// Validator is implemented by oracles which can check that a position is
+// valid before it is used to initialize them.
+type Validator interface {
+	// Validate checks the given position, and returns it with anything which
+	// isn't a part of it, like the operations of an EPD record, removed.
+	Validate(fen string) (string, error)
+}
+
 type Color uint8
 
 const (
@@ -35,3 +72,50 @@ const (
 	XtmWins
 	Draw
 )
+
+// validateBoard checks that the given piece placement field of a position
+// has the given number of ranks and files, separated by slashes, with empty
+// squares denoted by digits and pieces by the given characters. Promoted
+// pieces may be prefixed by a +, if promoted is set.
+func validateBoard(placement string, files, ranks int, pieces string, promoted bool) error {
+	rows := strings.Split(placement, "/")
+	if len(rows) != ranks {
+		return fmt.Errorf("board has %d ranks instead of %d", len(rows), ranks)
+	}
+
+	for i, row := range rows {
+		squares := 0
+		for j := 0; j < len(row); j++ {
+			switch char := row[j]; {
+			case char >= '1' && char <= '9':
+				squares += int(char - '0')
+			case char == '+' && promoted && j+1 < len(row) && strings.IndexByte(pieces, row[j+1]) >= 0:
+			case strings.IndexByte(pieces, char) >= 0:
+				squares++
+			default:
+				return fmt.Errorf("invalid character %q in rank %d", char, i+1)
+			}
+		}
+
+		if squares != files {
+			return fmt.Errorf("rank %d has %d squares instead of %d", i+1, squares, files)
+		}
+	}
+
+	return nil
+}
+
+// counters returns the number of the given fields, starting from index
+// first, which are valid move counters, upto a maximum of n counters.
+func counters(fields []string, first, n int) int {
+	count := 0
+	for i := first; i < len(fields) && count < n; i++ {
+		if _, err := strconv.ParseUint(strings.TrimSuffix(fields[i], ";"), 10, 32); err != nil {
+			break
+		}
+
+		count++
+	}
+
+	return count
+}
@@ -0,0 +1,111 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package games
+
+import "strings"
+
+// PolyglotKeys is the table of random numbers, called Random64 in the
+// Polyglot book format specification, which is used to compute the Zobrist
+// keys of the positions in Polyglot opening books.
+type PolyglotKeys [781]uint64
+
+// Offsets of the different kinds of keys in the table.
+const (
+	polyglotCastling  = 768
+	polyglotEnPassant = 772
+	polyglotTurn      = 780
+)
+
+// PolyglotKey returns the Zobrist key of the current position which is used
+// by Polyglot opening books, computed with the given table of keys.
+func (oracle *ChessOracle) PolyglotKey(keys *PolyglotKeys) uint64 {
+	return polyglotKey(oracle.FEN(), keys)
+}
+
+// polyglotKey returns the Polyglot Zobrist key of the position with the
+// given FEN, computed with the given table of keys.
+func polyglotKey(fen string, keys *PolyglotKeys) uint64 {
+	fields := strings.Fields(fen)
+	squares := chessSquares(fields[0])
+	white := fields[1] == "w"
+
+	var key uint64
+	for square, piece := range squares {
+		if piece == 0 {
+			continue
+		}
+
+		// Pieces are ordered as black pawn, white pawn, black knight, etc.
+		kind := 2 * strings.IndexByte("pnbrqk", lower(piece))
+		if piece != lower(piece) {
+			kind++
+		}
+
+		key ^= keys[64*kind+square]
+	}
+
+	for i, right := range "KQkq" {
+		if strings.ContainsRune(fields[2], right) {
+			key ^= keys[polyglotCastling+i]
+		}
+	}
+
+	// The en passant square is only hashed if a pawn of the side to move
+	// can actually capture on it.
+	if ep := fields[3]; ep != "-" {
+		file := int(ep[0] - 'a')
+		rank, pawn := 4, byte('P')
+		if !white {
+			rank, pawn = 3, 'p'
+		}
+
+		if (file > 0 && squares[8*rank+file-1] == pawn) ||
+			(file < 7 && squares[8*rank+file+1] == pawn) {
+			key ^= keys[polyglotEnPassant+file]
+		}
+	}
+
+	if white {
+		key ^= keys[polyglotTurn]
+	}
+
+	return key
+}
+
+// PolyglotMove converts a move from a Polyglot book into the UCI notation
+// in the current position. Polyglot books encode castling as the king
+// capturing its own rook, which is converted to the king's actual move.
+func (oracle *ChessOracle) PolyglotMove(move uint16) string {
+	to := int(move & 0x3f)
+	from := int(move>>6) & 0x3f
+	promotion := int(move>>12) & 0x7
+
+	squares := chessSquares(strings.Fields(oracle.FEN())[0])
+	king, rook := squares[from], squares[to]
+	if lower(king) == 'k' && lower(rook) == 'r' && (king == 'k') == (rook == 'r') {
+		switch {
+		case to > from:
+			to = from + 2
+		case to < from:
+			to = from - 2
+		}
+	}
+
+	str := chessSquare(from) + chessSquare(to)
+	if promotion > 0 && promotion <= 4 {
+		str += string(" nbrq"[promotion])
+	}
+
+	return str
+}
@@ -0,0 +1,58 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package games
+
+import "testing"
+
+func TestPolyglotKey(t *testing.T) {
+	// Each key is distinct, so that the keys of a position identify the
+	// entries of the table which were used for it.
+	var keys PolyglotKeys
+	for i := range keys {
+		keys[i] = uint64(i+1) * 0x9e3779b97f4a7c15
+	}
+
+	// The entries are 64*kind+square for the pieces, with the kinds ordered
+	// as black pawn, white pawn, black knight, and so on, followed by the
+	// castling rights, the en passant file, and the side to move.
+	tests := []struct {
+		name    string
+		fen     string
+		entries []int
+	}{
+		{"kings", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", []int{708, 700, 780}},
+		{"black to move", "4k3/8/8/8/8/8/8/4K3 b - - 0 1", []int{708, 700}},
+		{
+			"castling rights",
+			"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			[]int{448, 455, 708, 440, 447, 700, 768, 769, 770, 771, 780},
+		},
+		{"partial castling rights", "r3k2r/8/8/8/8/8/8/R3K2R b Kq - 0 1", []int{448, 455, 708, 440, 447, 700, 768, 771}},
+		{"capturable en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", []int{100, 35, 708, 700, 775, 780}},
+		{"uncapturable en passant", "4k3/8/8/3p4/4P3/8/8/4K3 w - d6 0 1", []int{92, 35, 708, 700, 780}},
+		{"black en passant", "4k3/8/8/8/Pp6/8/8/4K3 b - a3 0 1", []int{88, 25, 708, 700, 772}},
+		{"black en passant on the h file", "4k3/8/8/8/6pP/8/8/4K3 b - h3 0 1", []int{95, 30, 708, 700, 779}},
+	}
+
+	for _, test := range tests {
+		var want uint64
+		for _, entry := range test.entries {
+			want ^= keys[entry]
+		}
+
+		if got := polyglotKey(test.fen, &keys); got != want {
+			t.Errorf("%s: key of %s: got %016x, want %016x", test.name, test.fen, got, want)
+		}
+	}
+}
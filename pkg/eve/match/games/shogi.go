@@ -0,0 +1,715 @@
+package games
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ShogiOracle is an oracle for the game of Shogi. Positions are represented
+// with SFEN strings and moves in the USI move format. Sente, the player who
+// moves first, is represented by White, and Gote by Black.
+type ShogiOracle struct {
+	position shogiPosition
+
+	// history stores the keys of all the positions reached in the game, and
+	// checks if the move which reached each position was a check, which are
+	// used to detect repetitions and perpetual checks.
+	history []string
+	checks  []bool
+
+	// declared is set when the side to move wins the game by an entering
+	// king declaration.
+	declared bool
+}
+
+// ShogiStartpos is the SFEN of the standard starting position of Shogi.
+const ShogiStartpos = "lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1"
+
+// shogiMaxPly is the ply after which a game is declared drawn, as is common
+// in computer shogi tournaments, since games with both kings in the enemy
+// camp can otherwise go on forever.
+const shogiMaxPly = 512
+
+func (oracle *ShogiOracle) Initialize(sfen string) {
+	if sfen == "startpos" {
+		sfen = ShogiStartpos
+	}
+
+	oracle.position.SetSFEN(sfen)
+	oracle.history = []string{oracle.position.Key()}
+	oracle.checks = []bool{false}
+	oracle.declared = false
+}
+
+func (oracle *ShogiOracle) SideToMove() Color {
+	return oracle.position.turn
+}
+
+func (oracle *ShogiOracle) MakeMove(movstr string) error {
+	// Entering king declarations are sent as a move by USI engines.
+	if movstr == "win" {
+		if !oracle.position.CanDeclare() {
+			return errors.New("illegal entering king declaration")
+		}
+
+		oracle.position.turn ^= 1
+		oracle.declared = true
+		return nil
+	}
+
+	for _, move := range oracle.position.LegalMoves() {
+		if move.String() == movstr {
+			oracle.position.MakeMove(move)
+			oracle.history = append(oracle.history, oracle.position.Key())
+			oracle.checks = append(oracle.checks, oracle.position.InCheck(oracle.position.turn))
+			return nil
+		}
+	}
+
+	return errors.New("illegal move")
+}
+
+// handRegexp matches the pieces in hand field of a SFEN.
+var handRegexp = regexp.MustCompile(`^(-|([0-9]*[PLNSGBRplnsgbr])+)$`)
+
+func (oracle *ShogiOracle) Validate(sfen string) (string, error) {
+	if sfen == "startpos" {
+		return sfen, nil
+	}
+
+	fields := strings.Fields(sfen)
+	if len(fields) < 3 {
+		return "", errors.New("sfen has less than 3 fields")
+	}
+
+	// The move number is optional, and anything after it is ignored.
+	fields = fields[:3+counters(fields, 3, 1)]
+	for i := 3; i < len(fields); i++ {
+		fields[i] = strings.TrimSuffix(fields[i], ";")
+	}
+
+	if err := validateBoard(fields[0], 9, 9, "plnsgbrkPLNSGBRK", true); err != nil {
+		return "", err
+	}
+
+	if strings.Count(fields[0], "K") > 1 || strings.Count(fields[0], "k") > 1 {
+		return "", errors.New("a side has more than one king")
+	}
+
+	if fields[1] != "b" && fields[1] != "w" {
+		return "", fmt.Errorf("invalid side to move %s", fields[1])
+	}
+
+	if !handRegexp.MatchString(fields[2]) {
+		return "", fmt.Errorf("invalid pieces in hand %s", fields[2])
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+func (oracle *ShogiOracle) LegalMoves() []string {
+	var moves []string
+	for _, move := range oracle.position.LegalMoves() {
+		moves = append(moves, move.String())
+	}
+
+	return moves
+}
+
+func (oracle *ShogiOracle) FEN() string {
+	return oracle.position.SFEN()
+}
+
+func (oracle *ShogiOracle) GameResult() (Result, string) {
+	if oracle.declared {
+		return XtmWins, "Entering King Declaration"
+	}
+
+	// In shogi, a player with no legal moves loses even if it isn't in check,
+	// though this is only possible in composed positions.
+	if len(oracle.position.LegalMoves()) == 0 {
+		if oracle.position.InCheck(oracle.position.turn) {
+			return XtmWins, "Checkmate"
+		}
+
+		return XtmWins, "No Legal Moves"
+	}
+
+	// Sennichite: the game is drawn if the same position occurs four times,
+	// unless one of the players gave check with every move in the cycle.
+	current := len(oracle.history) - 1
+	first, occurrences := current, 0
+	for i := current; i >= 0; i-- {
+		if oracle.history[i] == oracle.history[current] {
+			first = i
+			occurrences++
+		}
+	}
+
+	if occurrences >= 4 {
+		// Moves in the cycle alternate between the players, and the last one
+		// was made by the side not to move.
+		perpetual := [2]bool{true, true}
+		for i := first + 1; i <= current; i++ {
+			mover := (current - i) % 2 // 0 for xtm, 1 for stm
+			perpetual[mover] = perpetual[mover] && oracle.checks[i]
+		}
+
+		switch {
+		case perpetual[0]:
+			return StmWins, "Perpetual Check"
+		case perpetual[1]:
+			return XtmWins, "Perpetual Check"
+		default:
+			return Draw, "Sennichite"
+		}
+	}
+
+	if oracle.position.ply > shogiMaxPly {
+		return Draw, "Max Moves"
+	}
+
+	return Ongoing, ""
+}
+
+// ZeroMoves always reports false, since the engines need the full history
+// of the game to be able to detect repetitions.
+func (oracle *ShogiOracle) ZeroMoves() bool {
+	return false
+}
+
+// shogiKind represents the kind of a shogi piece.
+type shogiKind uint8
+
+const (
+	shogiNone shogiKind = iota
+	shogiPawn
+	shogiLance
+	shogiKnight
+	shogiSilver
+	shogiGold
+	shogiBishop
+	shogiRook
+	shogiKing
+	shogiTokin
+	shogiPromotedLance
+	shogiPromotedKnight
+	shogiPromotedSilver
+	shogiHorse
+	shogiDragon
+)
+
+// shogiLetters are the SFEN letters of the unpromoted piece kinds.
+const shogiLetters = "PLNSGBRK"
+
+// shogiPromoted and shogiUnpromoted map the kinds of the pieces to their promoted and
+// unpromoted counterparts. Kinds which can't promote map to shogiNone.
+var shogiPromoted = [...]shogiKind{
+	shogiPawn:   shogiTokin,
+	shogiLance:  shogiPromotedLance,
+	shogiKnight: shogiPromotedKnight,
+	shogiSilver: shogiPromotedSilver,
+	shogiBishop: shogiHorse,
+	shogiRook:   shogiDragon,
+	shogiDragon: shogiNone,
+}
+
+var shogiUnpromoted = [...]shogiKind{
+	shogiPawn:           shogiPawn,
+	shogiLance:          shogiLance,
+	shogiKnight:         shogiKnight,
+	shogiSilver:         shogiSilver,
+	shogiGold:           shogiGold,
+	shogiBishop:         shogiBishop,
+	shogiRook:           shogiRook,
+	shogiKing:           shogiKing,
+	shogiTokin:          shogiPawn,
+	shogiPromotedLance:  shogiLance,
+	shogiPromotedKnight: shogiKnight,
+	shogiPromotedSilver: shogiSilver,
+	shogiHorse:          shogiBishop,
+	shogiDragon:         shogiRook,
+}
+
+// shogiDirection is a (rank, file) offset from Sente's point of view, where
+// a negative rank offset is towards Gote's side of the board.
+type shogiDirection struct{ rank, file int }
+
+var (
+	shogiOrthogonal = []shogiDirection{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	shogiDiagonal   = []shogiDirection{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+	shogiGoldSteps  = []shogiDirection{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, 0}}
+)
+
+// shogiSteps and shogiSlides are the directions in which each kind of piece
+// can move by a single step, and any number of steps, respectively.
+var shogiSteps = [...][]shogiDirection{
+	shogiPawn:           {{-1, 0}},
+	shogiKnight:         {{-2, -1}, {-2, 1}},
+	shogiSilver:         {{-1, -1}, {-1, 0}, {-1, 1}, {1, -1}, {1, 1}},
+	shogiGold:           shogiGoldSteps,
+	shogiKing:           append(append([]shogiDirection{}, shogiOrthogonal...), shogiDiagonal...),
+	shogiTokin:          shogiGoldSteps,
+	shogiPromotedLance:  shogiGoldSteps,
+	shogiPromotedKnight: shogiGoldSteps,
+	shogiPromotedSilver: shogiGoldSteps,
+	shogiHorse:          shogiOrthogonal,
+	shogiDragon:         shogiDiagonal,
+}
+
+var shogiSlides = [...][]shogiDirection{
+	shogiLance:  {{-1, 0}},
+	shogiBishop: shogiDiagonal,
+	shogiRook:   shogiOrthogonal,
+	shogiHorse:  shogiDiagonal,
+	shogiDragon: shogiOrthogonal,
+}
+
+// shogiPiece represents a piece on the board. The zero value represents an
+// empty square.
+type shogiPiece struct {
+	kind  shogiKind
+	color Color
+}
+
+// String returns the SFEN representation of the piece.
+func (piece shogiPiece) String() string {
+	letter := string(shogiLetters[shogiUnpromoted[piece.kind]-1])
+	if piece.color == Black {
+		letter = strings.ToLower(letter)
+	}
+
+	if piece.kind != shogiUnpromoted[piece.kind] {
+		return "+" + letter
+	}
+
+	return letter
+}
+
+// shogiMove represents a move in shogi. Drops have a from square of -1.
+type shogiMove struct {
+	from, to int
+	drop     shogiKind
+	promote  bool
+}
+
+// String returns the USI representation of the move.
+func (move shogiMove) String() string {
+	if move.from < 0 {
+		return string(shogiLetters[move.drop-1]) + "*" + shogiSquare(move.to)
+	}
+
+	str := shogiSquare(move.from) + shogiSquare(move.to)
+	if move.promote {
+		str += "+"
+	}
+
+	return str
+}
+
+// shogiSquare returns the USI representation of the given square. Squares
+// are indexed from the top left of the board from Sente's point of view, so
+// the first square is 9a and the last one is 1i.
+func shogiSquare(square int) string {
+	return fmt.Sprintf("%d%c", 9-square%9, 'a'+square/9)
+}
+
+// shogiPosition represents a position in a game of shogi.
+type shogiPosition struct {
+	board [81]shogiPiece
+	hands [2][shogiKing]int // indexed by the kind of the pieces
+	turn  Color
+	ply   int
+}
+
+// SetSFEN sets the position to the one described by the given SFEN string.
+func (pos *shogiPosition) SetSFEN(sfen string) {
+	*pos = shogiPosition{ply: 1}
+	fields := strings.Fields(sfen)
+
+	// Pieces
+	if len(fields) >= 1 {
+		square, promoted := 0, false
+		for _, char := range fields[0] {
+			switch {
+			case char == '/':
+			case char == '+':
+				promoted = true
+			case char >= '1' && char <= '9':
+				square += int(char - '0')
+			default:
+				kind := shogiKind(strings.IndexRune(shogiLetters, unicode.ToUpper(char)) + 1)
+				if kind == shogiNone || square >= len(pos.board) {
+					continue
+				}
+
+				color := White
+				if char >= 'a' {
+					color = Black
+				}
+
+				if promoted {
+					kind, promoted = shogiPromoted[kind], false
+				}
+
+				pos.board[square] = shogiPiece{kind: kind, color: color}
+				square++
+			}
+		}
+	}
+
+	// Turn
+	if len(fields) >= 2 && fields[1] == "w" {
+		pos.turn = Black
+	}
+
+	// Pieces in hand
+	if len(fields) >= 3 && fields[2] != "-" {
+		count := 0
+		for _, char := range fields[2] {
+			if char >= '0' && char <= '9' {
+				count = count*10 + int(char-'0')
+				continue
+			}
+
+			kind := shogiKind(strings.IndexRune(shogiLetters, unicode.ToUpper(char)) + 1)
+			if kind != shogiNone && kind != shogiKing {
+				color := White
+				if char >= 'a' {
+					color = Black
+				}
+
+				if count == 0 {
+					count = 1
+				}
+
+				pos.hands[color][kind] += count
+			}
+
+			count = 0
+		}
+	}
+
+	// Move number
+	if len(fields) >= 4 {
+		pos.ply, _ = strconv.Atoi(fields[3])
+	}
+}
+
+// SFEN returns the SFEN string of the position.
+func (pos *shogiPosition) SFEN() string {
+	return pos.Key() + " " + strconv.Itoa(pos.ply)
+}
+
+// Key returns the SFEN string of the position without the move number,
+// which is the same for all occurrences of the position in a game.
+func (pos *shogiPosition) Key() string {
+	var sfen strings.Builder
+
+	for rank := 0; rank < 9; rank++ {
+		empty := 0
+		for file := 0; file < 9; file++ {
+			piece := pos.board[rank*9+file]
+			if piece.kind == shogiNone {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				sfen.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+
+			sfen.WriteString(piece.String())
+		}
+
+		if empty > 0 {
+			sfen.WriteString(strconv.Itoa(empty))
+		}
+
+		if rank < 8 {
+			sfen.WriteByte('/')
+		}
+	}
+
+	if pos.turn == White {
+		sfen.WriteString(" b ")
+	} else {
+		sfen.WriteString(" w ")
+	}
+
+	// Pieces in hand are written in the order of their value.
+	hands := ""
+	for _, color := range []Color{White, Black} {
+		for kind := shogiRook; kind >= shogiPawn; kind-- {
+			count := pos.hands[color][kind]
+			if count == 0 {
+				continue
+			}
+
+			if count > 1 {
+				hands += strconv.Itoa(count)
+			}
+
+			hands += shogiPiece{kind: kind, color: color}.String()
+		}
+	}
+
+	if hands == "" {
+		hands = "-"
+	}
+
+	sfen.WriteString(hands)
+	return sfen.String()
+}
+
+// MakeMove plays the given move on the position. The move is assumed to be
+// legal in the position.
+func (pos *shogiPosition) MakeMove(move shogiMove) {
+	if move.from < 0 {
+		pos.board[move.to] = shogiPiece{kind: move.drop, color: pos.turn}
+		pos.hands[pos.turn][move.drop]--
+	} else {
+		// Captured pieces go to the capturer's hand, unpromoted.
+		if captured := pos.board[move.to]; captured.kind != shogiNone {
+			pos.hands[pos.turn][shogiUnpromoted[captured.kind]]++
+		}
+
+		piece := pos.board[move.from]
+		if move.promote {
+			piece.kind = shogiPromoted[piece.kind]
+		}
+
+		pos.board[move.to] = piece
+		pos.board[move.from] = shogiPiece{}
+	}
+
+	pos.turn ^= 1
+	pos.ply++
+}
+
+// LegalMoves returns a list of all the legal moves in the position.
+func (pos *shogiPosition) LegalMoves() []shogiMove {
+	return pos.legalMoves(true)
+}
+
+// legalMoves returns a list of the legal moves in the position. Checkmating
+// pawn drops are only excluded if uchifuzume is set, which is used to avoid
+// recursing when checking if a pawn drop is a checkmate.
+func (pos *shogiPosition) legalMoves(uchifuzume bool) []shogiMove {
+	var legal []shogiMove
+	for _, move := range pos.pseudoLegalMoves() {
+		child := *pos
+		child.MakeMove(move)
+
+		// Moves which leave the king in check are illegal.
+		if child.InCheck(pos.turn) {
+			continue
+		}
+
+		// Uchifuzume: pawn drops which checkmate the opponent are illegal.
+		if uchifuzume && move.from < 0 && move.drop == shogiPawn &&
+			child.InCheck(child.turn) && len(child.legalMoves(false)) == 0 {
+			continue
+		}
+
+		legal = append(legal, move)
+	}
+
+	return legal
+}
+
+// pseudoLegalMoves returns a list of all the moves in the position, without
+// checking if they leave the king in check or are checkmating pawn drops.
+func (pos *shogiPosition) pseudoLegalMoves() []shogiMove {
+	var moves []shogiMove
+
+	for from, piece := range pos.board {
+		if piece.kind == shogiNone || piece.color != pos.turn {
+			continue
+		}
+
+		for _, to := range pos.attacks(from) {
+			if target := pos.board[to]; target.kind != shogiNone && target.color == pos.turn {
+				continue
+			}
+
+			canPromote := shogiPromoted[piece.kind] != shogiNone &&
+				(pos.inEnemyCamp(from, pos.turn) || pos.inEnemyCamp(to, pos.turn))
+			if !pos.isStuck(piece.kind, to, pos.turn) {
+				moves = append(moves, shogiMove{from: from, to: to})
+			}
+
+			if canPromote {
+				moves = append(moves, shogiMove{from: from, to: to, promote: true})
+			}
+		}
+	}
+
+	// Nifu: pawns can't be dropped on files which already have an unpromoted
+	// pawn of the same color.
+	var pawnFiles [9]bool
+	for square, piece := range pos.board {
+		if piece.kind == shogiPawn && piece.color == pos.turn {
+			pawnFiles[square%9] = true
+		}
+	}
+
+	for kind := shogiPawn; kind < shogiKing; kind++ {
+		if pos.hands[pos.turn][kind] == 0 {
+			continue
+		}
+
+		for to, piece := range pos.board {
+			if piece.kind != shogiNone || pos.isStuck(kind, to, pos.turn) ||
+				(kind == shogiPawn && pawnFiles[to%9]) {
+				continue
+			}
+
+			moves = append(moves, shogiMove{from: -1, to: to, drop: kind})
+		}
+	}
+
+	return moves
+}
+
+// attacks returns the squares attacked by the piece on the given square.
+func (pos *shogiPosition) attacks(square int) []int {
+	piece := pos.board[square]
+	rank, file := square/9, square%9
+
+	// The directions are from Sente's point of view.
+	forward := 1
+	if piece.color == Black {
+		forward = -1
+	}
+
+	var attacks []int
+	for _, dir := range shogiSteps[piece.kind] {
+		r, f := rank+dir.rank*forward, file+dir.file
+		if r >= 0 && r < 9 && f >= 0 && f < 9 {
+			attacks = append(attacks, r*9+f)
+		}
+	}
+
+	for _, dir := range shogiSlides[piece.kind] {
+		r, f := rank+dir.rank*forward, file+dir.file
+		for r >= 0 && r < 9 && f >= 0 && f < 9 {
+			attacks = append(attacks, r*9+f)
+			if pos.board[r*9+f].kind != shogiNone {
+				break
+			}
+
+			r, f = r+dir.rank*forward, f+dir.file
+		}
+	}
+
+	return attacks
+}
+
+// InCheck checks if the king of the given color is in check.
+func (pos *shogiPosition) InCheck(color Color) bool {
+	king := -1
+	for square, piece := range pos.board {
+		if piece.kind == shogiKing && piece.color == color {
+			king = square
+			break
+		}
+	}
+
+	// Composed positions may not have a king.
+	if king < 0 {
+		return false
+	}
+
+	for square, piece := range pos.board {
+		if piece.kind == shogiNone || piece.color == color {
+			continue
+		}
+
+		for _, attack := range pos.attacks(square) {
+			if attack == king {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// CanDeclare checks if the side to move can win the game by an entering
+// king declaration, according to the 27-point rule used by the CSA.
+func (pos *shogiPosition) CanDeclare() bool {
+	if pos.InCheck(pos.turn) {
+		return false
+	}
+
+	kingEntered, pieces, points := false, 0, 0
+	for square, piece := range pos.board {
+		if piece.kind == shogiNone || piece.color != pos.turn || !pos.inEnemyCamp(square, pos.turn) {
+			continue
+		}
+
+		if piece.kind == shogiKing {
+			kingEntered = true
+			continue
+		}
+
+		pieces++
+		points += shogiPoints(piece.kind)
+	}
+
+	for kind := shogiPawn; kind < shogiKing; kind++ {
+		points += pos.hands[pos.turn][kind] * shogiPoints(kind)
+	}
+
+	// Gote needs one point less than Sente, to make up for moving second.
+	required := 28
+	if pos.turn == Black {
+		required = 27
+	}
+
+	return kingEntered && pieces >= 10 && points >= required
+}
+
+// shogiPoints returns the number of points a piece of the given kind is
+// worth in an entering king declaration.
+func shogiPoints(kind shogiKind) int {
+	if kind := shogiUnpromoted[kind]; kind == shogiBishop || kind == shogiRook {
+		return 5
+	}
+
+	return 1
+}
+
+// inEnemyCamp checks if the given square is in the promotion zone of the
+// given color, which consists of the three ranks furthest from it.
+func (pos *shogiPosition) inEnemyCamp(square int, color Color) bool {
+	if color == White {
+		return square/9 < 3
+	}
+
+	return square/9 > 5
+}
+
+// isStuck checks if an unpromoted piece of the given kind and color would
+// have no moves on the given square, which is illegal.
+func (pos *shogiPosition) isStuck(kind shogiKind, square int, color Color) bool {
+	rank := square / 9
+	if color == Black {
+		rank = 8 - rank
+	}
+
+	switch kind {
+	case shogiPawn, shogiLance:
+		return rank < 1
+	case shogiKnight:
+		return rank < 2
+	default:
+		return false
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package games
+
+import (
+	"slices"
+	"testing"
+)
+
+// shogiPerft returns the number of leaf nodes of the move tree of the given
+// position with the given depth.
+func shogiPerft(pos shogiPosition, depth int) int {
+	moves := pos.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, move := range moves {
+		child := pos
+		child.MakeMove(move)
+		nodes += shogiPerft(child, depth-1)
+	}
+
+	return nodes
+}
+
+func TestShogiPerft(t *testing.T) {
+	// The reference counts of the starting position and of the Matsuri
+	// position, which has drops, promotions, and pinned pieces.
+	tests := []struct {
+		sfen  string
+		nodes []int
+	}{
+		{ShogiStartpos, []int{30, 900, 25470, 719731}},
+		{"l6nl/5+P1gk/2np1S3/p1p4Pp/3P2Sp1/1PPb2P1P/P5GS1/R8/LN4bKL w RGgsn5p 1", []int{207, 28684, 4809015}},
+	}
+
+	for _, test := range tests {
+		var pos shogiPosition
+		pos.SetSFEN(test.sfen)
+
+		for i, nodes := range test.nodes {
+			depth := i + 1
+			if testing.Short() && depth > 2 {
+				break
+			}
+
+			if got := shogiPerft(pos, depth); got != nodes {
+				t.Errorf("perft(%d) of %s: got %d, want %d", depth, test.sfen, got, nodes)
+			}
+		}
+	}
+}
+
+func TestShogiMoves(t *testing.T) {
+	tests := []struct {
+		name    string
+		sfen    string
+		legal   []string
+		illegal []string
+	}{
+		{
+			name:  "promotion is optional when entering the camp",
+			sfen:  "4k4/9/9/P8/9/9/9/9/4K4 b - 1",
+			legal: []string{"9d9c", "9d9c+"},
+		},
+		{
+			name:    "pawns promote on the last rank",
+			sfen:    "4k4/P8/9/9/9/9/9/9/4K4 b - 1",
+			legal:   []string{"9b9a+"},
+			illegal: []string{"9b9a"},
+		},
+		{
+			name:    "knights promote on the last two ranks",
+			sfen:    "4k4/9/9/N8/9/9/9/9/4K4 b - 1",
+			legal:   []string{"9d8b+"},
+			illegal: []string{"9d8b"},
+		},
+		{
+			name:    "gote promotes on sente's side of the board",
+			sfen:    "4k4/9/9/9/9/p8/9/9/4K4 w - 1",
+			legal:   []string{"9f9g", "9f9g+"},
+			illegal: []string{"9f9e"},
+		},
+		{
+			name:    "pieces are dropped where they can move",
+			sfen:    "4k4/9/9/9/9/9/9/9/4K4 b LNG 1",
+			legal:   []string{"L*9b", "N*9c", "G*9a", "G*5b"},
+			illegal: []string{"L*9a", "N*9a", "N*9b", "G*5a"},
+		},
+		{
+			name:    "nifu",
+			sfen:    "4k4/9/9/9/9/9/P8/9/4K4 b P 1",
+			legal:   []string{"P*8e"},
+			illegal: []string{"P*9e", "P*9a"},
+		},
+		{
+			name:    "uchifuzume",
+			sfen:    "8k/6S2/7G1/9/9/9/9/9/4K4 b P 1",
+			legal:   []string{"P*1c"},
+			illegal: []string{"P*1b"},
+		},
+		{
+			name:  "pawn drop checks which aren't mate",
+			sfen:  "8k/9/7G1/9/9/9/9/9/4K4 b P 1",
+			legal: []string{"P*1b"},
+		},
+		{
+			name:    "pinned pieces",
+			sfen:    "4r4/9/9/9/9/9/9/4G4/4K4 b - 1",
+			legal:   []string{"5h5g", "5i4i"},
+			illegal: []string{"5h4h", "5h6g"},
+		},
+	}
+
+	for _, test := range tests {
+		var oracle ShogiOracle
+		oracle.Initialize(test.sfen)
+		moves := oracle.LegalMoves()
+
+		for _, move := range test.legal {
+			if !slices.Contains(moves, move) {
+				t.Errorf("%s: %s is not legal in %s", test.name, move, test.sfen)
+			}
+		}
+
+		for _, move := range test.illegal {
+			if slices.Contains(moves, move) {
+				t.Errorf("%s: %s is legal in %s", test.name, move, test.sfen)
+			}
+		}
+	}
+}
+
+func TestShogiResult(t *testing.T) {
+	// cycle repeats the given moves, which return to the starting position,
+	// the given number of times.
+	cycle := func(count int, moves ...string) []string {
+		var cycle []string
+		for i := 0; i < count; i++ {
+			cycle = append(cycle, moves...)
+		}
+
+		return cycle
+	}
+
+	tests := []struct {
+		name   string
+		sfen   string
+		moves  []string
+		result Result
+		reason string
+	}{
+		{
+			name:   "checkmate",
+			sfen:   "8k/6S2/7G1/9/9/9/9/9/4K4 b G 1",
+			moves:  []string{"G*1b"},
+			result: XtmWins,
+			reason: "Checkmate",
+		},
+		{
+			name:   "the third repetition",
+			sfen:   "4k4/9/9/9/9/9/9/9/4K4 b - 1",
+			moves:  cycle(3, "5i5h", "5a5b", "5h5i", "5b5a")[:11],
+			result: Ongoing,
+		},
+		{
+			name:   "sennichite",
+			sfen:   "4k4/9/9/9/9/9/9/9/4K4 b - 1",
+			moves:  cycle(3, "5i5h", "5a5b", "5h5i", "5b5a"),
+			result: Draw,
+			reason: "Sennichite",
+		},
+		{
+			name:   "perpetual check",
+			sfen:   "4k4/9/9/9/9/9/9/9/K3R4 w - 1",
+			moves:  cycle(3, "5a4a", "5i4i", "4a5a", "4i5i"),
+			result: StmWins,
+			reason: "Perpetual Check",
+		},
+		{
+			name:   "declaration",
+			sfen:   "LNSGRGSNL/B3K4/9/9/9/9/9/9/4k4 b 10P 1",
+			moves:  []string{"win"},
+			result: XtmWins,
+			reason: "Entering King Declaration",
+		},
+		{
+			name:   "gote's declaration",
+			sfen:   "4K4/9/9/9/9/9/9/b3k4/lnsgrgsnl w 9p 1",
+			moves:  []string{"win"},
+			result: XtmWins,
+			reason: "Entering King Declaration",
+		},
+	}
+
+	for _, test := range tests {
+		var oracle ShogiOracle
+		oracle.Initialize(test.sfen)
+		for _, move := range test.moves {
+			if err := oracle.MakeMove(move); err != nil {
+				t.Fatalf("%s: %s: %v", test.name, move, err)
+			}
+		}
+
+		result, reason := oracle.GameResult()
+		if result != test.result || reason != test.reason {
+			t.Errorf("%s: got %v %q, want %v %q", test.name, result, reason, test.result, test.reason)
+		}
+	}
+}
+
+func TestShogiDeclaration(t *testing.T) {
+	tests := []struct {
+		name string
+		sfen string
+		can  bool
+	}{
+		{"28 points", "LNSGRGSNL/B3K4/9/9/9/9/9/9/4k4 b 10P 1", true},
+		{"sente needs 28 points", "LNSGRGSNL/B3K4/9/9/9/9/9/9/4k4 b 9P 1", false},
+		{"gote needs 27 points", "4K4/9/9/9/9/9/9/b3k4/lnsgrgsnl w 9p 1", true},
+		{"10 pieces in the camp", "LNSGRGSNL/4K4/9/9/9/9/9/9/4k4 b B10P 1", false},
+		{"king in the camp", "LNSGRGSNL/B8/9/4K4/9/9/9/9/4k4 b 10P 1", false},
+		{"not in check", "LNSGRGSNL/B3K4/9/9/9/9/9/9/4r3k b 10P 1", false},
+	}
+
+	for _, test := range tests {
+		var oracle ShogiOracle
+		oracle.Initialize(test.sfen)
+		if err := oracle.MakeMove("win"); (err == nil) != test.can {
+			t.Errorf("%s: declaration in %s: got %v, want %v", test.name, test.sfen, err == nil, test.can)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
+)
+
+// GenerateConfig configures the generation of openings by GenerateOpenings.
+type GenerateConfig struct {
+	// Game is the game the openings are generated for, and FEN is the
+	// position they are generated from, which defaults to the game's
+	// starting position.
+	Game string
+	FEN  string
+
+	// Count is the number of openings to generate.
+	Count int
+
+	// Plies is the number of random plies played from the starting position
+	// for each opening. EnginePlies is the number of plies played after them
+	// by the reference engine, which makes the playouts semi-random.
+	Plies       int
+	EnginePlies int
+
+	// Seed seeds the selection of the random moves, and is generated from
+	// the current time if it is zero.
+	Seed uint64
+
+	// Engine is the reference engine, if any. Generated openings are only
+	// kept if the absolute value of the engine's score for them, from a short
+	// search, is at most Threshold centipawns.
+	Engine    *EngineConfig
+	Threshold int
+}
+
+var ErrNoMoveGenerator = errors.New("generate openings: game oracle can't generate moves")
+
+// maxAttempts is the maximum number of playouts tried for every opening.
+const maxAttempts = 100
+
+// GenerateOpenings generates openings from random playouts with the given
+// configuration, and calls the given function with each unique opening. It
+// returns the number of openings generated, which is less than the number
+// asked for if enough openings couldn't be found.
+func GenerateOpenings(config GenerateConfig, write func(fen string) error) (int, error) {
+	oracle := games.GetOracle(config.Game)
+	if oracle == nil {
+		return 0, fmt.Errorf("generate openings: no oracle for game %s", config.Game)
+	}
+
+	generator, ok := oracle.(games.MoveGenerator)
+	if !ok {
+		return 0, ErrNoMoveGenerator
+	}
+
+	if config.FEN == "" {
+		config.FEN = games.Startpos(config.Game)
+	}
+
+	if config.Seed == 0 {
+		config.Seed = uint64(time.Now().UnixNano()) | 1
+	}
+
+	var rand prng
+	rand.Seed(config.Seed)
+
+	var engine *Engine
+	if config.Engine != nil {
+		var err error
+		if engine, err = StartEngine(*config.Engine, "generate"); err != nil {
+			return 0, err
+		}
+
+		defer engine.Kill()
+	} else if config.EnginePlies > 0 {
+		return 0, errors.New("generate openings: engine plies need a reference engine")
+	}
+
+	generated := 0
+	seen := map[string]bool{}
+	for attempts := 0; generated < config.Count && attempts < maxAttempts*config.Count; attempts++ {
+		oracle.Initialize(config.FEN)
+		moves := []string{}
+
+		ok := true
+		for ply := 0; ok && ply < config.Plies+config.EnginePlies; ply++ {
+			var move string
+			if ply < config.Plies {
+				legal := generator.LegalMoves()
+				if len(legal) == 0 {
+					ok = false
+					break
+				}
+
+				move = legal[rand.Uint64()%uint64(len(legal))]
+			} else {
+				var err error
+				move, _, err = search(engine, Position{FEN: config.FEN, Moves: moves})
+				if err != nil && !errors.Is(err, ErrNoScore) {
+					return generated, err
+				}
+			}
+
+			if err := oracle.MakeMove(move); err != nil {
+				ok = false
+				break
+			}
+
+			moves = append(moves, move)
+
+			// Playouts which end the game aren't openings.
+			if result, _ := oracle.GameResult(); result != games.Ongoing {
+				ok = false
+			}
+		}
+
+		fen := oracle.FEN()
+		if !ok || seen[fen] {
+			continue
+		}
+
+		seen[fen] = true
+
+		// Keep only the openings which are balanced according to the engine.
+		// Openings the engine didn't score can't be judged, and are skipped.
+		if engine != nil {
+			_, score, err := search(engine, Position{FEN: config.FEN, Moves: moves})
+			switch {
+			case errors.Is(err, ErrNoScore):
+				continue
+			case err != nil:
+				return generated, err
+			case score.Mate || score.Value > config.Threshold || score.Value < -config.Threshold:
+				continue
+			}
+		}
+
+		if err := write(fen); err != nil {
+			return generated, err
+		}
+
+		generated++
+	}
+
+	return generated, nil
+}
+
+// search makes the engine search the given position with its time control
+// and returns its best move and its score.
+func search(engine *Engine, position Position) (string, Score, error) {
+	tc, err := ParseTime(engine.config.TimeC)
+	if err != nil {
+		return "", Score{}, err
+	}
+
+	if err := engine.Position(position); err != nil {
+		return "", Score{}, err
+	}
+
+	if err := engine.Synchronize(); err != nil {
+		return "", Score{}, err
+	}
+
+	if err := engine.Go(Clock{White: tc, Black: tc}); err != nil {
+		return "", Score{}, err
+	}
+
+	limit := tc.Base + tc.Byoyomi
+	if tc.MoveTime > 0 {
+		limit = tc.MoveTime
+	}
+
+	margin := time.Duration(engine.config.TimeMargin) * time.Millisecond
+	move, _, err := engine.BestMove(limit + margin)
+	if err != nil {
+		return "", Score{}, err
+	}
+
+	score, err := engine.Score()
+	return move, score, err
+}
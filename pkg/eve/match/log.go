@@ -0,0 +1,226 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// engineLog writes the diagnostics of an engine to a log file, a new one of
+// which is opened for every game the engine plays. The engine's stderr is
+// always logged, and its protocol transcript is logged if it is enabled.
+//
+// Log files are stored as <directory>/<engine>/<game>.log, and only the
+// given number of most recent log files of each engine are retained. The
+// methods of an engineLog can be called on a nil engineLog, which logs
+// nothing, and is used for engines with logging disabled.
+type engineLog struct {
+	sync.Mutex
+
+	directory  string
+	transcript bool
+	keep       int
+
+	file *os.File
+}
+
+// openLogs stores the paths of the log files which are open, which aren't
+// removed by the rotation of other engineLogs logging to the same directory,
+// such as those of the same engine played by different threads.
+var openLogs = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: map[string]bool{}}
+
+// newEngineLog returns a new engineLog for the engine with the given config.
+// It returns nil if logging is disabled for the engine.
+func newEngineLog(config EngineConfig) *engineLog {
+	if config.Stderr == "" {
+		return nil
+	}
+
+	return &engineLog{
+		directory:  filepath.Join(config.Stderr, config.Name),
+		transcript: config.Transcript,
+		keep:       config.LogKeep,
+	}
+}
+
+// Open closes the current log file and opens a new one for the given game.
+func (log *engineLog) Open(game string) error {
+	if log == nil {
+		return nil
+	}
+
+	log.Lock()
+	defer log.Unlock()
+
+	log.close()
+
+	if err := os.MkdirAll(log.directory, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filepath.Join(log.directory, sanitize(game)+".log"))
+	if err != nil {
+		return err
+	}
+
+	log.file = file
+
+	openLogs.Lock()
+	openLogs.paths[file.Name()] = true
+	openLogs.Unlock()
+
+	log.rotate()
+	return nil
+}
+
+// Stderr logs a line written by the engine to its stderr.
+func (log *engineLog) Stderr(line string) {
+	log.write("!", line)
+}
+
+// Sent logs a line sent to the engine, if transcripts are enabled.
+func (log *engineLog) Sent(line string) {
+	if log != nil && log.transcript {
+		log.write("<", line)
+	}
+}
+
+// Received logs a line received from the engine, if transcripts are enabled.
+func (log *engineLog) Received(line string) {
+	if log != nil && log.transcript {
+		log.write(">", line)
+	}
+}
+
+// Close closes the current log file.
+func (log *engineLog) Close() {
+	if log == nil {
+		return
+	}
+
+	log.Lock()
+	defer log.Unlock()
+
+	log.close()
+}
+
+// Path returns the path to the current log file.
+func (log *engineLog) Path() string {
+	if log == nil {
+		return ""
+	}
+
+	log.Lock()
+	defer log.Unlock()
+
+	if log.file == nil {
+		return ""
+	}
+
+	return log.file.Name()
+}
+
+func (log *engineLog) write(direction, line string) {
+	if log == nil {
+		return
+	}
+
+	log.Lock()
+	defer log.Unlock()
+
+	if log.file != nil {
+		_, _ = fmt.Fprintf(log.file, "%s %s\n", direction, line)
+	}
+}
+
+func (log *engineLog) close() {
+	if log.file != nil {
+		openLogs.Lock()
+		delete(openLogs.paths, log.file.Name())
+		openLogs.Unlock()
+
+		_ = log.file.Close()
+		log.file = nil
+	}
+}
+
+// rotate deletes the oldest log files of the engine, so that only the most
+// recent ones are retained. A keep of zero retains all of the log files.
+func (log *engineLog) rotate() {
+	if log.keep <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(log.directory)
+	if err != nil {
+		return
+	}
+
+	type logFile struct {
+		name string
+		time int64
+	}
+
+	files := make([]logFile, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		files = append(files, logFile{entry.Name(), info.ModTime().UnixNano()})
+	}
+
+	// Newest files first.
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].time > files[j].time
+	})
+
+	// Never remove the log files which are being written to.
+	openLogs.Lock()
+	defer openLogs.Unlock()
+
+	for i := log.keep; i < len(files); i++ {
+		path := filepath.Join(log.directory, files[i].name)
+		if openLogs.paths[path] {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			logrus.Warnf("failed to remove old engine log: %v", err)
+		}
+	}
+}
+
+// sanitize makes the given name safe to use as a file name.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', ' ':
+			return '_'
+		default:
+			return r
+		}
+	}, name)
+}
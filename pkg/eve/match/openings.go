@@ -1,71 +1,268 @@
 package match
 
 import (
-	"os"
-	"strings"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 type OpeningConfig struct {
 	File string
-	// Format string // only EPD opening files supported.
+
+	// Format is the format of the opening book, which is either epd, for
+	// books with an opening position on each line, pgn, for books of chess
+	// openings as PGN games, or polyglot, for Polyglot books of chess
+	// openings. Books with a .pgn or .bin extension are assumed to be in the
+	// pgn or polyglot formats, and other books in the epd format.
+	Format string
+
+	// Order is the order in which openings are selected from an epd or pgn
+	// book, which is either sequential, random, where each opening is
+	// selected randomly, or shuffle, where the book is shuffled once and then
+	// iterated over sequentially. Openings are always selected randomly from
+	// Polyglot books.
 	Order string
+
+	// Seed seeds the random selection of openings, and is generated from the
+	// current time if it is zero. Start is the index of the first opening in
+	// the sequential and shuffle orders.
+	Seed  uint64
 	Start uint64
+
+	// Cursor is the number of openings already selected from the book. A book
+	// opened with the same seed and a non-zero cursor continues the sequence
+	// of openings from where it was left off, without repeating any of them.
+	Cursor uint64
+
+	// Repeat is the number of consecutive games played with each opening,
+	// with the engines swapping colours between them. It defaults to 2, so
+	// that both the games of every pair share an opening.
+	Repeat int
+
+	// Depth is the maximum number of plies played from the openings of a pgn
+	// or Polyglot book, or zero to play all of them. If MinDepth is also set,
+	// the number of plies is selected randomly for each opening from between
+	// MinDepth and Depth, both inclusive.
+	Depth    int
+	MinDepth int
+
+	// Keys is the file with the table of 781 random numbers, Random64 in the
+	// Polyglot specification, which is used to find the positions in a
+	// Polyglot book.
+	Keys string
 }
 
-// NewBook opens the opening book with the given configuration.
-func NewBook(config OpeningConfig) (*OpeningBook, error) {
+// NewBook opens the opening book with the given configuration. Openings
+// from epd and pgn books are validated and deduplicated with the oracle of
+// the given game, and books with invalid openings aren't opened.
+func NewBook(game string, config OpeningConfig) (*OpeningBook, error) {
 	var book OpeningBook
 
-	book.prng.Seed(config.Start)
-	if config.Start == 0 {
-		book.prng.Seed(uint64(time.Now().UnixMilli()))
+	// The seed is stored in the book's config, so that the same sequence of
+	// openings is selected if the book is opened again with it.
+	if config.Seed == 0 {
+		config.Seed = uint64(time.Now().UnixNano()) | 1
 	}
 
-	// Read the opening book file.
-	file, err := os.ReadFile(config.File)
+	book.OpeningConfig = config
+
+	if config.format() == "polyglot" {
+		var err error
+		if book.polyglot, err = readPolyglotBook(config.File, config.Keys); err != nil {
+			return nil, err
+		}
+
+		book.replay()
+		return &book, nil
+	}
+
+	entries, err := readBook(config)
 	if err != nil {
 		return nil, err
 	}
 
-	// Only epd books are supported currently. Split the book by newline to get
-	// each opening, which are all situated on separate lines.
-	book.entries = strings.Split(string(file), "\n")
-	for i, entry := range book.entries {
-		book.entries[i] = strings.Trim(entry, "\n\r\t ")
+	var summary BookSummary
+	book.entries, summary = validateBook(game, entries)
+
+	switch {
+	case len(summary.Invalid) > 0:
+		return nil, fmt.Errorf(
+			"new book: %s: %w (%d invalid openings)",
+			config.File, summary.Invalid[0], len(summary.Invalid),
+		)
+	case len(book.entries) == 0:
+		return nil, fmt.Errorf("new book: no openings in %s", config.File)
+	case summary.Duplicates > 0:
+		logrus.Warnf("new book: removed %d duplicate openings from %s", summary.Duplicates, config.File)
 	}
-	book.OpeningConfig = config
 
+	book.replay()
 	return &book, nil
 }
 
+// replay selects the openings which were selected before the book's cursor
+// again, so that the book continues the sequence of openings from it.
+func (book *OpeningBook) replay() {
+	book.prng.Seed(book.Seed)
+
+	switch book.Order {
+	case "sequential", "random", "":
+	case "shuffle":
+		// Shuffle the openings with a Fisher-Yates shuffle.
+		book.order = make([]int, len(book.entries))
+		for i := range book.order {
+			book.order[i] = i
+		}
+
+		for i := len(book.order) - 1; i > 0; i-- {
+			j := int(book.prng.Uint64() % uint64(i+1))
+			book.order[i], book.order[j] = book.order[j], book.order[i]
+		}
+	default:
+		logrus.Warnf("new book: unknown order %s, using sequential", book.Order)
+	}
+
+	cursor := book.Cursor
+	for book.Cursor = 0; ; book.Cursor++ {
+		book.selectOpening()
+		if book.Cursor == cursor {
+			break
+		}
+	}
+}
+
+// format returns the format of the book, which is guessed from the book's
+// extension if it isn't specified.
+func (config OpeningConfig) format() string {
+	if config.Format != "" {
+		return config.Format
+	}
+
+	switch filepath.Ext(config.File) {
+	case ".pgn":
+		return "pgn"
+	case ".bin":
+		return "polyglot"
+	default:
+		return "epd"
+	}
+}
+
+// GamesPerOpening returns the number of consecutive games which are played
+// with each opening of the book.
+func (config OpeningConfig) GamesPerOpening() int {
+	if config.Repeat <= 0 {
+		return 2
+	}
+
+	return config.Repeat
+}
+
 // OpeningBook represents a complete opening book complete with an opening
 // selection strategy and state.
 type OpeningBook struct {
+	sync.Mutex
+
 	OpeningConfig
 	prng    prng
-	entries []string
+	entries []Opening
+
+	// order stores the indices of the openings in the order they are
+	// selected in, if the book is shuffled. index is the index of the
+	// current opening, and depth is the number of plies of it which are
+	// played.
+	order []int
+	index int
+	depth int
+
+	// The Polyglot book, if the book is in that format, and the opening
+	// currently selected from it.
+	polyglot *polyglotBook
+	opening  Opening
+}
+
+// Opening is an opening selected from an opening book.
+type Opening struct {
+	Position
+
+	// Eval is the evaluation of the opening in centipawns, from the point of
+	// view of its side to move, if HasEval is set. Openings are tagged with
+	// their evaluations by the ce operation in EPD books.
+	Eval    int
+	HasEval bool
 }
 
 // Next makes the book select a new opening.
 func (book *OpeningBook) Next() {
+	book.Cursor++
+	book.selectOpening()
+}
+
+// Take returns the currently selected opening and makes the book select a
+// new one. It is safe to call from multiple goroutines.
+func (book *OpeningBook) Take() Opening {
+	book.Lock()
+	defer book.Unlock()
+
+	opening := book.Current()
+	book.Next()
+	return opening
+}
+
+// selectOpening selects the opening at the book's cursor.
+func (book *OpeningBook) selectOpening() {
+	book.depth = book.selectDepth()
+
+	// Openings are always selected randomly from Polyglot books.
+	if book.polyglot != nil {
+		book.opening = Opening{Position: book.polyglot.Opening(&book.prng, book.depth)}
+		return
+	}
+
+	n := uint64(len(book.entries))
 	switch book.Order {
 	case "random":
-		book.Start = book.prng.Uint64() % uint64(len(book.entries))
+		book.index = int(book.prng.Uint64() % n)
+	case "shuffle":
+		book.index = book.order[(book.Start+book.Cursor)%n]
 	default:
-		book.Start = (book.Start + 1) % uint64(len(book.entries))
+		book.index = int((book.Start + book.Cursor) % n)
 	}
 }
 
 // Current returns the currently selected opening.
-func (book *OpeningBook) Current() string {
-	return book.entries[book.Start]
+func (book *OpeningBook) Current() Opening {
+	if book.polyglot != nil {
+		return book.opening
+	}
+
+	opening := book.entries[book.index]
+	if book.depth > 0 && len(opening.Moves) > book.depth {
+		opening.Moves = opening.Moves[:book.depth]
+	}
+
+	return opening
 }
 
+// selectDepth selects the number of plies to play from the next opening.
+func (book *OpeningBook) selectDepth() int {
+	if book.MinDepth <= 0 || book.MinDepth >= book.Depth {
+		return book.Depth
+	}
+
+	return book.MinDepth + int(book.prng.Uint64()%uint64(book.Depth-book.MinDepth+1))
+}
+
+// Wrap returns the config of the book, which opens the book at the opening
+// which is currently selected.
 func (book *OpeningBook) Wrap() OpeningConfig {
-	config := book.OpeningConfig
-	config.Start = book.prng.seed
-	return config
+	book.Lock()
+	defer book.Unlock()
+
+	return book.OpeningConfig
 }
 
 // xorshift64star Pseudo-Random Number Generator
@@ -0,0 +1,182 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
+)
+
+// tagRegexp matches a tag pair of a PGN game.
+var tagRegexp = regexp.MustCompile(`^\[(\w+)\s+"(.*)"\]$`)
+
+// moveNumberRegexp matches a move number in the movetext of a PGN game.
+var moveNumberRegexp = regexp.MustCompile(`^[0-9]+\.*$|^[0-9]+\.+`)
+
+// readPGNBook reads the chess openings from the PGN file at the given path.
+// The SAN moves of each game are played through the chess oracle, and the
+// openings are returned as their starting position and their moves in UCI
+// notation. Games start from the position in their FEN tag, if any.
+func readPGNBook(path string) ([]bookEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []bookEntry
+
+	tags := map[string]string{}
+	movetext := ""
+	start := 0 // Line on which the current game starts.
+
+	// finish converts the game which was read last into an opening.
+	finish := func() {
+		if len(tags) > 0 || strings.TrimSpace(movetext) != "" {
+			opening, err := pgnOpening(tags, movetext)
+			entries = append(entries, bookEntry{Opening: Opening{Position: opening}, line: start, err: err})
+		}
+
+		tags, movetext, start = map[string]string{}, "", 0
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		// Lines starting with % are escaped, and are ignored.
+		if line == "" || strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		match := tagRegexp.FindStringSubmatch(line)
+
+		// A tag after some movetext starts a new game.
+		if match != nil && strings.TrimSpace(movetext) != "" {
+			finish()
+		}
+
+		if start == 0 {
+			start = i + 1
+		}
+
+		if match != nil {
+			tags[match[1]] = match[2]
+			continue
+		}
+
+		movetext += line + "\n"
+	}
+
+	finish()
+	return entries, nil
+}
+
+// pgnOpening converts the game with the given tags and movetext into an
+// opening position.
+func pgnOpening(tags map[string]string, movetext string) (Position, error) {
+	oracle := &games.ChessOracle{}
+
+	opening := Position{FEN: games.ChessStartpos}
+	if fen, found := tags["FEN"]; found {
+		var err error
+		if opening.FEN, err = oracle.Validate(fen); err != nil {
+			return opening, err
+		}
+	}
+
+	oracle.Initialize(opening.FEN)
+
+	for _, san := range pgnMoves(movetext) {
+		move, err := oracle.ParseSAN(san)
+		if err != nil {
+			return opening, err
+		}
+
+		if err := oracle.MakeMove(move); err != nil {
+			return opening, fmt.Errorf("%w %s", err, san)
+		}
+
+		opening.Moves = append(opening.Moves, move)
+	}
+
+	return opening, nil
+}
+
+// pgnMoves returns the SAN moves of the main line of the given movetext.
+// Comments, variations, move numbers, annotations, and results are skipped.
+func pgnMoves(movetext string) []string {
+	var moves []string
+
+	depth := 0 // Nesting depth of variations.
+	for i := 0; i < len(movetext); {
+		switch char := movetext[i]; char {
+		case '{':
+			// Comments continue until the closing brace.
+			end := strings.IndexByte(movetext[i:], '}')
+			if end < 0 {
+				return moves
+			}
+
+			i += end + 1
+
+		case ';':
+			// Comments continue until the end of the line.
+			end := strings.IndexByte(movetext[i:], '\n')
+			if end < 0 {
+				return moves
+			}
+
+			i += end + 1
+
+		case '(':
+			depth++
+			i++
+
+		case ')':
+			depth--
+			i++
+
+		case ' ', '\t', '\r', '\n':
+			i++
+
+		default:
+			end := i
+			for end < len(movetext) && !strings.ContainsRune(" \t\r\n{};()", rune(movetext[end])) {
+				end++
+			}
+
+			token := movetext[i:end]
+			i = end
+
+			// Remove any move number preceding the move.
+			token = moveNumberRegexp.ReplaceAllString(token, "")
+
+			switch token {
+			case "", "1-0", "0-1", "1/2-1/2", "*", "e.p.":
+				continue
+			}
+
+			if depth > 0 || token[0] == '$' {
+				continue
+			}
+
+			moves = append(moves, token)
+		}
+	}
+
+	return moves
+}
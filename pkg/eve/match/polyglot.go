@@ -0,0 +1,180 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
+)
+
+// polyglotBook is a Polyglot opening book, which stores weighted moves for
+// the positions in it, identified by their Zobrist keys.
+type polyglotBook struct {
+	keys    games.PolyglotKeys
+	entries []polyglotEntry
+}
+
+// polyglotEntry is a single entry of a Polyglot book. The learn field of the
+// entries isn't used and isn't stored.
+type polyglotEntry struct {
+	key    uint64
+	move   uint16
+	weight uint16
+}
+
+// polyglotEntrySize is the size of an entry in a Polyglot book file.
+const polyglotEntrySize = 16
+
+// polyglotMaxPlies is the maximum length of the openings played from a book
+// without a depth, which would never end in a book with cycles.
+const polyglotMaxPlies = 100
+
+var ErrNoPolyglotKeys = errors.New("polyglot: no keys file for the book")
+
+// readPolyglotBook reads the Polyglot book at the given path, along with the
+// table of random numbers used to compute its keys from the given file.
+func readPolyglotBook(path, keys string) (*polyglotBook, error) {
+	var book polyglotBook
+
+	if keys == "" {
+		return nil, ErrNoPolyglotKeys
+	}
+
+	if err := readPolyglotKeys(keys, &book.keys); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data)%polyglotEntrySize != 0 {
+		return nil, fmt.Errorf("polyglot: %s: truncated book", path)
+	}
+
+	book.entries = make([]polyglotEntry, len(data)/polyglotEntrySize)
+	for i := range book.entries {
+		entry := data[i*polyglotEntrySize:]
+		book.entries[i] = polyglotEntry{
+			key:    binary.BigEndian.Uint64(entry[0:8]),
+			move:   binary.BigEndian.Uint16(entry[8:10]),
+			weight: binary.BigEndian.Uint16(entry[10:12]),
+		}
+	}
+
+	// Books are supposed to be sorted by key already, but a stable sort makes
+	// sure that the lookups work for books which aren't.
+	sort.SliceStable(book.entries, func(i, j int) bool {
+		return book.entries[i].key < book.entries[j].key
+	})
+
+	return &book, nil
+}
+
+// readPolyglotKeys reads the table of Polyglot random numbers from the file
+// at the given path, which contains the 781 numbers in hexadecimal, in the
+// order of the specification, separated by whitespace or commas.
+func readPolyglotKeys(path string, keys *games.PolyglotKeys) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	fields := strings.FieldsFunc(string(data), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+
+	if len(fields) != len(keys) {
+		return fmt.Errorf("polyglot: %s: expected %d keys, found %d", path, len(keys), len(fields))
+	}
+
+	for i, field := range fields {
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X")
+		field = strings.TrimRight(field, "ULul")
+		if keys[i], err = strconv.ParseUint(field, 16, 64); err != nil {
+			return fmt.Errorf("polyglot: %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// Opening plays a random opening from the book, with the moves in each
+// position selected randomly by their weights, and returns the moves from
+// the starting position. At most depth plies are played if depth is positive,
+// and at most polyglotMaxPlies otherwise.
+func (book *polyglotBook) Opening(prng *prng, depth int) Position {
+	opening := Position{FEN: games.ChessStartpos}
+
+	oracle := &games.ChessOracle{}
+	oracle.Initialize(opening.FEN)
+
+	if depth <= 0 {
+		depth = polyglotMaxPlies
+	}
+
+	for ply := 0; ply < depth; ply++ {
+		entries := book.lookup(oracle.PolyglotKey(&book.keys))
+
+		total := uint64(0)
+		for _, entry := range entries {
+			total += uint64(entry.weight)
+		}
+
+		// Moves with zero weight are never played.
+		if total == 0 {
+			break
+		}
+
+		pick := prng.Uint64() % total
+		for _, entry := range entries {
+			if pick < uint64(entry.weight) {
+				// Books with illegal moves are followed as far as possible.
+				move := oracle.PolyglotMove(entry.move)
+				if err := oracle.MakeMove(move); err != nil {
+					return opening
+				}
+
+				opening.Moves = append(opening.Moves, move)
+				break
+			}
+
+			pick -= uint64(entry.weight)
+		}
+	}
+
+	return opening
+}
+
+// lookup returns the entries of the book for the position with the given key.
+func (book *polyglotBook) lookup(key uint64) []polyglotEntry {
+	start := sort.Search(len(book.entries), func(i int) bool {
+		return book.entries[i].key >= key
+	})
+
+	end := start
+	for end < len(book.entries) && book.entries[end].key == key {
+		end++
+	}
+
+	return book.entries[start:end]
+}
@@ -0,0 +1,108 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
+)
+
+func TestReadPolyglotKeys(t *testing.T) {
+	// Tables are accepted in the formats they are usually published in, as
+	// hex literals in C or Go source, or as plain hex numbers.
+	var lines []string
+	for i := 0; i < len(games.PolyglotKeys{}); i++ {
+		switch i % 3 {
+		case 0:
+			lines = append(lines, fmt.Sprintf("0x%016xULL,", i+1))
+		case 1:
+			lines = append(lines, "0X00000000000000FF,")
+		case 2:
+			lines = append(lines, "ff00")
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys games.PolyglotKeys
+	if err := readPolyglotKeys(path, &keys); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys[0] != 0x1 || keys[1] != 0xff || keys[2] != 0xff00 || keys[3] != 0x4 {
+		t.Errorf("got keys %x, want [1 ff ff00 4]", keys[:4])
+	}
+
+	// Tables with the wrong number of keys are rejected.
+	if err := os.WriteFile(path, []byte(strings.Join(lines[1:], "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := readPolyglotKeys(path, &keys); err == nil {
+		t.Error("a table with 780 keys was accepted")
+	}
+}
+
+// TestPolyglotReference checks the keys of the test positions listed in the
+// Polyglot book format specification. The table of random numbers isn't
+// distributed with arbiter, so the test needs the path of a file with it in
+// the ARBITER_POLYGLOT_KEYS environment variable.
+func TestPolyglotReference(t *testing.T) {
+	path := os.Getenv("ARBITER_POLYGLOT_KEYS")
+	if path == "" {
+		t.Skip("ARBITER_POLYGLOT_KEYS is not set")
+	}
+
+	var keys games.PolyglotKeys
+	if err := readPolyglotKeys(path, &keys); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		moves string
+		key   uint64
+	}{
+		{"", 0x463b96181691fc9c},
+		{"e2e4", 0x823c9b50fd114196},
+		{"e2e4 d7d5", 0x0756b94461c50fb0},
+		{"e2e4 d7d5 e4e5", 0x662fafb965db29d4},
+		{"e2e4 d7d5 e4e5 f7f5", 0x22a48b5a8e47ff78},
+		{"e2e4 d7d5 e4e5 f7f5 e1e2", 0x652a607ca3f242c1},
+		{"e2e4 d7d5 e4e5 f7f5 e1e2 e8f7", 0x00fdd303c946bdd9},
+		{"a2a4 b7b5 h2h4 b5b4 c2c4", 0x3c8123ea7b067637},
+		{"a2a4 b7b5 h2h4 b5b4 c2c4 b4c3 a1a3", 0x5c3f9b829b279560},
+	}
+
+	for _, test := range tests {
+		oracle := &games.ChessOracle{}
+		oracle.Initialize(games.ChessStartpos)
+		for _, move := range strings.Fields(test.moves) {
+			if err := oracle.MakeMove(move); err != nil {
+				t.Fatalf("%s: %s: %v", test.moves, move, err)
+			}
+		}
+
+		if got := oracle.PolyglotKey(&keys); got != test.key {
+			t.Errorf("key after %q: got %016x, want %016x", test.moves, got, test.key)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewPool creates a new engine Pool which keeps at most the given number of
+// idle engines running.
+func NewPool(capacity int) *Pool {
+	return &Pool{capacity: capacity}
+}
+
+// Pool is a pool of running engines which are reused between games instead
+// of being started again for every game, since process startup and loading
+// things like networks can take up a large share of the time of fast games.
+// A Pool is not safe for concurrent use, so every worker should use its own.
+type Pool struct {
+	capacity int
+	idle     []*Engine // least recently used first
+}
+
+// Get returns a running engine with the given configuration which is ready
+// for the game with the given name. An idle engine with the same
+// configuration is reused if possible, otherwise a new one is started.
+func (pool *Pool) Get(config EngineConfig, game string) (*Engine, error) {
+	for i, engine := range pool.idle {
+		if !reflect.DeepEqual(engine.config, config) {
+			continue
+		}
+
+		pool.idle = append(pool.idle[:i], pool.idle[i+1:]...)
+		if err := engine.OpenLog(game); err != nil {
+			_ = engine.Kill()
+			return nil, err
+		}
+
+		if err := engine.NewGame(); err == nil {
+			return engine, nil
+		}
+
+		// The engine has probably crashed, so start a new one instead.
+		logrus.Warnf("restarting engine %s: it failed to start a new game", config.Name)
+		_ = engine.Kill()
+		break
+	}
+
+	return StartEngine(config, game)
+}
+
+// Put returns the given engine to the pool after a game. Engines which can't
+// be reused are killed, and so is the least recently used idle engine if the
+// pool is full.
+func (pool *Pool) Put(engine *Engine) {
+	if !engine.Reusable() {
+		if path := engine.log.Path(); engine.err != nil && path != "" {
+			logrus.Warnf("engine %s crashed: see %s", engine.config.Name, path)
+		}
+
+		_ = engine.Kill()
+		return
+	}
+
+	pool.idle = append(pool.idle, engine)
+	for len(pool.idle) > pool.capacity {
+		_ = pool.idle[0].Kill()
+		pool.idle = pool.idle[1:]
+	}
+}
+
+// Close kills all the idle engines in the pool.
+func (pool *Pool) Close() {
+	for _, engine := range pool.idle {
+		_ = engine.Kill()
+	}
+
+	pool.idle = nil
+}
@@ -0,0 +1,137 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
+)
+
+// NewProtocol returns a new instance of the engine communication protocol
+// with the given name. An empty name defaults to UCI.
+func NewProtocol(name string) (Protocol, error) {
+	switch name {
+	case "":
+		return &UXI{Name: "uci"}, nil
+	case "uci", "uai", "ugi":
+		return &UXI{Name: name}, nil
+	case "usi":
+		return &USI{UXI{Name: name}}, nil
+	case "xboard", "cecp":
+		return &XBoard{}, nil
+	default:
+		return nil, fmt.Errorf("engine: unknown protocol %s", name)
+	}
+}
+
+// Protocol is the interface implemented by the various engine communication
+// protocols supported by arbiter. A Protocol may store state specific to the
+// engine it is being used with, so a new instance should be created for
+// every engine.
+type Protocol interface {
+	// Initialize initializes the engine on startup.
+	Initialize(engine *Engine) error
+
+	// NewGame prepares the engine for a new game.
+	NewGame(engine *Engine) error
+
+	// Synchronize waits for the engine to complete some time consuming task
+	// and synchronizes the interface with it.
+	Synchronize(engine *Engine) error
+
+	// Position sets up the given position on the engine.
+	Position(engine *Engine, position Position) error
+
+	// Go makes the engine start searching the current position with the
+	// given state of the clocks.
+	Go(engine *Engine, clock Clock) error
+
+	// BestMove waits for the engine to finish searching and returns the move
+	// it played. If the engine resigns instead, ErrResigned is returned. If
+	// the engine reports the move it expects in reply, it is stored in the
+	// Engine so that the engine can ponder on it.
+	BestMove(engine *Engine, timeout time.Duration) (string, error)
+
+	// Ponder makes the engine start pondering on the given position, which
+	// is the current position with the engine's expected reply played.
+	Ponder(engine *Engine, position Position, clock Clock) error
+
+	// PonderHit tells a pondering engine that the expected reply was played,
+	// so that it can continue its search normally.
+	PonderHit(engine *Engine) error
+
+	// Stop makes the engine stop searching immediately.
+	Stop(engine *Engine) error
+
+	// GameOver tells the engine that the game has ended with the given
+	// result, from the engine's point of view.
+	GameOver(engine *Engine, result Result) error
+
+	// Quit asks the engine to exit.
+	Quit(engine *Engine) error
+}
+
+// ErrResigned is returned by Protocol.BestMove if the engine resigns.
+var ErrResigned = errors.New("engine: resigned")
+
+// ErrFalseClaim is returned by Protocol.BestMove if the engine claims a game
+// result instead of playing a move. Arbiter adjudicates games by itself, so
+// a claim is only made by an engine when it disagrees with the adjudication.
+var ErrFalseClaim = errors.New("engine: false result claim")
+
+// Position represents a position in a game as a starting FEN and the moves
+// which have been played from it.
+type Position struct {
+	FEN   string
+	Moves []string
+}
+
+// String returns the position as its FEN followed by any moves.
+func (position Position) String() string {
+	if len(position.Moves) == 0 {
+		return position.FEN
+	}
+
+	return position.FEN + " moves " + strings.Join(position.Moves, " ")
+}
+
+// Clock represents the state of the clocks of both the sides of a game.
+type Clock struct {
+	White, Black TimeControl
+
+	// Turn is the color of the side to move.
+	Turn games.Color
+}
+
+// Us returns the time control of the side to move.
+func (clock Clock) Us() TimeControl {
+	if clock.Turn == games.White {
+		return clock.White
+	}
+
+	return clock.Black
+}
+
+// Them returns the time control of the side not to move.
+func (clock Clock) Them() TimeControl {
+	if clock.Turn == games.White {
+		return clock.Black
+	}
+
+	return clock.White
+}
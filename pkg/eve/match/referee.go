@@ -0,0 +1,178 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
+)
+
+var ErrRefereeDisagreement = errors.New("referee: referees disagree")
+
+// Referee is a games.Oracle which adjudicates a game by asking UGI engines
+// about its state with the query command, instead of using a built-in
+// implementation of the game's rules. If more than one engine is acting as
+// a referee, their responses are cross-checked against each other.
+type Referee struct {
+	engines []*Engine
+	indices []int
+
+	fen   string
+	moves []string
+
+	// Err stores the first error encountered while querying the referees,
+	// and Culprit the index of the engine which caused it. Since the Oracle
+	// interface has no way to report errors, these should be checked after
+	// every call to the Referee's methods.
+	Err     error
+	Culprit int
+}
+
+// NewReferee returns a new Referee which queries the engines among the given
+// ones that are marked as referees. It returns nil if there are none.
+func NewReferee(engines [2]*Engine) *Referee {
+	var referee Referee
+	for i, engine := range engines {
+		if engine.config.Referee {
+			referee.engines = append(referee.engines, engine)
+			referee.indices = append(referee.indices, i)
+		}
+	}
+
+	if len(referee.engines) == 0 {
+		return nil
+	}
+
+	return &referee
+}
+
+// HasReferee checks if any of the given engines is marked as a referee.
+func HasReferee(engines ...EngineConfig) bool {
+	for _, engine := range engines {
+		if engine.Referee {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (referee *Referee) Initialize(fen string) {
+	referee.fen = fen
+	referee.moves = nil
+}
+
+func (referee *Referee) SideToMove() games.Color {
+	response := referee.query("p1turn")
+	if response == "false" {
+		return games.Black
+	}
+
+	return games.White
+}
+
+// MakeMove adds the given move to the game. The legality of the move is not
+// checked, since UGI has no query for it; illegal moves are left to the
+// engines to complain about.
+func (referee *Referee) MakeMove(move string) error {
+	referee.moves = append(referee.moves, move)
+	return nil
+}
+
+func (referee *Referee) FEN() string {
+	return referee.fen
+}
+
+func (referee *Referee) GameResult() (games.Result, string) {
+	if referee.query("gameover") != "true" {
+		return games.Ongoing, ""
+	}
+
+	result := referee.query("result")
+	p1turn := referee.query("p1turn") == "true"
+
+	switch {
+	case referee.Err != nil:
+		return games.Ongoing, ""
+	case result == "draw":
+		return games.Draw, "Referee Adjudication"
+	case (result == "p1win") == p1turn:
+		return games.StmWins, "Referee Adjudication"
+	default:
+		return games.XtmWins, "Referee Adjudication"
+	}
+}
+
+// ZeroMoves always reports false, since the referees can't be queried for
+// the position's FEN, so the move list can never be reset.
+func (referee *Referee) ZeroMoves() bool {
+	return false
+}
+
+// query asks every referee the given query and returns the response. If
+// the referees disagree with each other, the disagreement is recorded as
+// an error, since it is impossible to decide which of them is right.
+func (referee *Referee) query(query string) string {
+	if referee.Err != nil {
+		return ""
+	}
+
+	responses := make([]string, len(referee.engines))
+	for i, engine := range referee.engines {
+		// A referee pondering on its opponent's time can't be sent anything
+		// but the end of its search, so its search is stopped first, and it
+		// searches anew when it is its turn.
+		if engine.Pondering() {
+			if err := engine.StopPonder(); err != nil {
+				referee.fail(i, err)
+				return ""
+			}
+		}
+
+		position := Position{FEN: referee.fen, Moves: referee.moves}
+		if err := engine.Position(position); err != nil {
+			referee.fail(i, err)
+			return ""
+		}
+
+		response, err := engine.Query(query)
+		if err != nil {
+			referee.fail(i, err)
+			return ""
+		}
+
+		responses[i] = response
+	}
+
+	for _, response := range responses[1:] {
+		if response != responses[0] {
+			logrus.Warnf("referees disagree on query %s: %q", query, responses)
+			referee.Err = ErrRefereeDisagreement
+			referee.Culprit = -1
+			return ""
+		}
+	}
+
+	return responses[0]
+}
+
+// fail records the given error caused by the ith referee.
+func (referee *Referee) fail(i int, err error) {
+	referee.Err = fmt.Errorf("referee: %w", err)
+	referee.Culprit = referee.indices[i]
+}
@@ -2,6 +2,8 @@ package match
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -9,19 +11,44 @@ import (
 
 // TimeControl stores the time control configuration for an engine.
 type TimeControl struct {
+	// MovesToGo is the number of moves in each period of a cyclic time
+	// control, after which Base is added to the clock again. While a game is
+	// being played, it is the number of moves left in the current period.
 	MovesToGo int
 	Base, Inc time.Duration
+
+	// Byoyomi is the extra time given for every move once the base time has
+	// run out. It is used instead of an increment.
+	Byoyomi time.Duration
+
+	// MoveTime is the fixed time per move, if any, in which case the other
+	// fields are ignored.
+	MoveTime time.Duration
 }
 
 // ParseTime parses the given time-control configuration string into a
 // TimeControl object. The string should have a format of
 // movestogo/time+increment, where both time and increment in seconds. The
 // movestogo part is optional and maybe omitted for a non-cyclic time control.
+// A byoyomi period can be used instead of an increment by suffixing it with
+// b, like 600+10b. A fixed time per move can be specified instead with the
+// format time/move.
 func ParseTime(time_str string) (TimeControl, error) {
 	var tc TimeControl
 
 	// Split the string into 'movestogo' and 'time+increment' parts.
 	moves_str, time_str, found := strings.Cut(time_str, "/")
+	if found && time_str == "move" {
+		// Parse the fixed time per move.
+		secs, err := strconv.ParseFloat(moves_str, 32)
+		if err != nil {
+			return TimeControl{}, err
+		}
+
+		tc.MoveTime = time.Millisecond * time.Duration(math.Round(secs*1000))
+		return tc, nil
+	}
+
 	tc.MovesToGo = -1
 	var err error
 	if found {
@@ -30,6 +57,10 @@ func ParseTime(time_str string) (TimeControl, error) {
 		if err != nil {
 			return TimeControl{}, err
 		}
+
+		if tc.MovesToGo <= 0 {
+			return TimeControl{}, fmt.Errorf("parse tc: invalid movestogo %d", tc.MovesToGo)
+		}
 	} else {
 		// If there is no movestogo part, moves_str is the time_str.
 		time_str = moves_str
@@ -42,8 +73,9 @@ func ParseTime(time_str string) (TimeControl, error) {
 		return TimeControl{}, errors.New("parse tc: increment not found")
 	}
 
-	// Parse the increment string.
-	incs, err := strconv.ParseFloat(inc_str, 32)
+	// Parse the increment string, which might be a byoyomi period.
+	byoyomi_str, is_byoyomi := strings.CutSuffix(inc_str, "b")
+	incs, err := strconv.ParseFloat(byoyomi_str, 32)
 	if err != nil {
 		return TimeControl{}, err
 	}
@@ -54,7 +86,112 @@ func ParseTime(time_str string) (TimeControl, error) {
 		return TimeControl{}, err
 	}
 
-	tc.Inc = time.Millisecond * time.Duration(incs*1000)
-	tc.Base = time.Millisecond * time.Duration(secs*1000)
+	if is_byoyomi {
+		tc.Byoyomi = time.Millisecond * time.Duration(math.Round(incs*1000))
+	} else {
+		tc.Inc = time.Millisecond * time.Duration(math.Round(incs*1000))
+	}
+
+	tc.Base = time.Millisecond * time.Duration(math.Round(secs*1000))
 	return tc, nil
 }
+
+// String returns the time control in the format accepted by ParseTime.
+func (tc TimeControl) String() string {
+	seconds := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	}
+
+	if tc.MoveTime > 0 {
+		return seconds(tc.MoveTime) + "/move"
+	}
+
+	str := seconds(tc.Base) + "+"
+	if tc.Byoyomi > 0 {
+		str += seconds(tc.Byoyomi) + "b"
+	} else {
+		str += seconds(tc.Inc)
+	}
+
+	if tc.MovesToGo > 0 {
+		str = strconv.Itoa(tc.MovesToGo) + "/" + str
+	}
+
+	return str
+}
+
+// Scale returns the time control with all of its times multiplied by the
+// given ratio, for playing games with time odds.
+func (tc TimeControl) Scale(ratio float64) TimeControl {
+	scale := func(d time.Duration) time.Duration {
+		return time.Duration(float64(d) * ratio).Round(time.Millisecond)
+	}
+
+	tc.Base, tc.Inc = scale(tc.Base), scale(tc.Inc)
+	tc.Byoyomi, tc.MoveTime = scale(tc.Byoyomi), scale(tc.MoveTime)
+	return tc
+}
+
+// TimeOdds returns the given engines with the time control of the first one
+// multiplied by the given ratio, so that it plays the others with time odds.
+// A ratio of zero or one leaves the engines unchanged.
+func TimeOdds(engines []EngineConfig, ratio float64) ([]EngineConfig, error) {
+	engines = append([]EngineConfig(nil), engines...)
+	if ratio == 0 || ratio == 1 || len(engines) == 0 {
+		return engines, nil
+	}
+
+	if ratio < 0 {
+		return nil, fmt.Errorf("time odds: ratio %g is negative", ratio)
+	}
+
+	tc, err := ParseTime(engines[0].TimeC)
+	if err != nil {
+		return nil, fmt.Errorf("time odds: %s: %w", engines[0].Name, err)
+	}
+
+	engines[0].TimeC = tc.Scale(ratio).String()
+	return engines, nil
+}
+
+// TimeUsage stores statistics about the time used by an engine.
+type TimeUsage struct {
+	Moves      int
+	Total, Max time.Duration
+
+	// Overruns is the number of moves for which the engine exceeded its
+	// clock, but stayed within its time margin. Forfeits is the number of
+	// games it lost on time, after exceeding its time margin.
+	Overruns int
+	Forfeits int
+}
+
+// Record adds a move which took the given time to the statistics.
+func (usage *TimeUsage) Record(spent time.Duration) {
+	usage.Moves++
+	usage.Total += spent
+	if spent > usage.Max {
+		usage.Max = spent
+	}
+}
+
+// Add adds the given statistics to the current ones.
+func (usage *TimeUsage) Add(other TimeUsage) {
+	usage.Moves += other.Moves
+	usage.Total += other.Total
+	if other.Max > usage.Max {
+		usage.Max = other.Max
+	}
+
+	usage.Overruns += other.Overruns
+	usage.Forfeits += other.Forfeits
+}
+
+// Average returns the average time taken by the engine per move.
+func (usage TimeUsage) Average() time.Duration {
+	if usage.Moves == 0 {
+		return 0
+	}
+
+	return usage.Total / time.Duration(usage.Moves)
+}
@@ -0,0 +1,121 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// USI implements the Universal Shogi Interface. It is similar to the other
+// UXI protocols, except that positions are described with SFEN strings, and
+// Sente, the side which moves first and is called White by arbiter, is the
+// side called black in the protocol.
+type USI struct {
+	UXI
+}
+
+func (usi *USI) Initialize(engine *Engine) error {
+	return usi.initialize(engine, "USI_Ponder")
+}
+
+func (usi *USI) NewGame(engine *Engine) error {
+	// Engines load things like their evaluation files when they receive
+	// isready, which has to be answered before a game is started.
+	if err := usi.Synchronize(engine); err != nil {
+		return err
+	}
+
+	return engine.Write("usinewgame")
+}
+
+func (usi *USI) Position(engine *Engine, position Position) error {
+	// The starting position has its own keyword, and isn't an sfen.
+	command := "position sfen " + position.FEN
+	if position.FEN == "startpos" {
+		command = "position startpos"
+	}
+
+	if len(position.Moves) == 0 {
+		return engine.Write("%s", command)
+	}
+
+	return engine.Write("%s moves %s", command, strings.Join(position.Moves, " "))
+}
+
+func (usi *USI) Go(engine *Engine, clock Clock) error {
+	return engine.Write("go" + usi.limits(engine, clock))
+}
+
+func (usi *USI) Ponder(engine *Engine, position Position, clock Clock) error {
+	if err := usi.Position(engine, position); err != nil {
+		return err
+	}
+
+	return engine.Write("go ponder" + usi.limits(engine, clock))
+}
+
+// limits returns the search limits of a go command with the given clock.
+func (usi *USI) limits(engine *Engine, clock Clock) string {
+	if us := clock.Us(); us.MoveTime > 0 {
+		// USI has no movetime, so byoyomi with no main time is used instead.
+		return fmt.Sprintf(" btime 0 wtime 0 byoyomi %d", us.MoveTime.Milliseconds()) +
+			fixedLimits(engine)
+	}
+
+	limits := fmt.Sprintf(
+		" btime %d wtime %d",
+		clock.White.Base.Milliseconds(),
+		clock.Black.Base.Milliseconds(),
+	)
+
+	if us := clock.Us(); us.Byoyomi > 0 {
+		limits += fmt.Sprintf(" byoyomi %d", us.Byoyomi.Milliseconds())
+	} else {
+		limits += fmt.Sprintf(
+			" binc %d winc %d",
+			clock.White.Inc.Milliseconds(),
+			clock.Black.Inc.Milliseconds(),
+		)
+	}
+
+	return limits + fixedLimits(engine)
+}
+
+func (usi *USI) GameOver(engine *Engine, result Result) error {
+	switch result {
+	case Win:
+		return engine.Write("gameover win")
+	case Loss:
+		return engine.Write("gameover lose")
+	default:
+		return engine.Write("gameover draw")
+	}
+}
+
+func (usi *USI) BestMove(engine *Engine, timeout time.Duration) (string, error) {
+	move, err := usi.UXI.BestMove(engine, timeout)
+	if err != nil {
+		return "", err
+	}
+
+	// Entering king declarations are sent as the move win, and are checked
+	// by the oracle like any other move.
+	if move == "resign" {
+		return "", ErrResigned
+	}
+
+	return move, nil
+}
@@ -0,0 +1,183 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UXI implements the family of UCI-like protocols, like UCI for Chess, UAI
+// for Ataxx, and UGI for other games. The protocols are identical except for
+// the name of the protocol being used in some commands.
+type UXI struct {
+	// Name is the name of the protocol, like uci, uai, or ugi.
+	Name string
+}
+
+func (uxi *UXI) Initialize(engine *Engine) error {
+	return uxi.initialize(engine, "Ponder")
+}
+
+// initialize initializes the engine, with ponder being the name of the
+// option which tells the engine that it will be allowed to ponder.
+func (uxi *UXI) initialize(engine *Engine, ponder string) error {
+	if err := engine.Write(uxi.Name); err != nil {
+		return err
+	}
+
+	if _, err := engine.Await(uxi.Name+"ok", 5*time.Second); err != nil {
+		return err
+	}
+
+	// Set the options in a sorted order so that the commands sent to the
+	// engine are the same every time it is initialized.
+	names := make([]string, 0, len(engine.config.Options))
+	for name := range engine.config.Options {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		value := engine.config.Options[name]
+		if err := engine.Write("setoption name %s value %s", name, value); err != nil {
+			return err
+		}
+	}
+
+	// Engines need to be told that they will be allowed to ponder, unless
+	// the option has already been set explicitly.
+	if _, found := engine.config.Options[ponder]; engine.config.Ponder && !found {
+		return engine.Write("setoption name %s value true", ponder)
+	}
+
+	return nil
+}
+
+func (uxi *UXI) NewGame(engine *Engine) error {
+	if err := engine.Write(uxi.Name + "newgame"); err != nil {
+		return err
+	}
+
+	return uxi.Synchronize(engine)
+}
+
+func (uxi *UXI) Synchronize(engine *Engine) error {
+	if err := engine.Write("isready"); err != nil {
+		return err
+	}
+
+	_, err := engine.Await("readyok", 5*time.Second)
+	return err
+}
+
+func (uxi *UXI) Position(engine *Engine, position Position) error {
+	if len(position.Moves) == 0 {
+		return engine.Write("position fen %s", position.FEN)
+	}
+
+	return engine.Write("position fen %s moves %s", position.FEN, strings.Join(position.Moves, " "))
+}
+
+func (uxi *UXI) Go(engine *Engine, clock Clock) error {
+	return engine.Write("go" + uxi.limits(engine, clock))
+}
+
+func (uxi *UXI) Ponder(engine *Engine, position Position, clock Clock) error {
+	if err := uxi.Position(engine, position); err != nil {
+		return err
+	}
+
+	return engine.Write("go ponder" + uxi.limits(engine, clock))
+}
+
+func (uxi *UXI) PonderHit(engine *Engine) error {
+	return engine.Write("ponderhit")
+}
+
+func (uxi *UXI) Stop(engine *Engine) error {
+	return engine.Write("stop")
+}
+
+func (uxi *UXI) GameOver(engine *Engine, result Result) error {
+	// The protocols have no command for the end of a game.
+	return nil
+}
+
+// limits returns the search limits of a go command with the given clock.
+func (uxi *UXI) limits(engine *Engine, clock Clock) string {
+	// UGI engines use player based names for the clocks instead of colors.
+	names := [4]string{"wtime", "btime", "winc", "binc"}
+	if uxi.Name == "ugi" {
+		names = [4]string{"p1time", "p2time", "p1inc", "p2inc"}
+	}
+
+	limits := ""
+	if us := clock.Us(); us.MoveTime > 0 {
+		limits += fmt.Sprintf(" movetime %d", us.MoveTime.Milliseconds())
+	} else {
+		limits += fmt.Sprintf(
+			" %s %d %s %d %s %d %s %d",
+			names[0], clock.White.Base.Milliseconds(),
+			names[1], clock.Black.Base.Milliseconds(),
+			names[2], clock.White.Inc.Milliseconds(),
+			names[3], clock.Black.Inc.Milliseconds(),
+		)
+
+		if us.MovesToGo > 0 {
+			limits += fmt.Sprintf(" movestogo %d", us.MovesToGo)
+		}
+	}
+
+	return limits + fixedLimits(engine)
+}
+
+// fixedLimits returns the depth and node limits of the engine's searches.
+func fixedLimits(engine *Engine) string {
+	limits := ""
+	if engine.config.Depth > 0 {
+		limits += fmt.Sprintf(" depth %d", engine.config.Depth)
+	}
+
+	if engine.config.Nodes > 0 {
+		limits += fmt.Sprintf(" nodes %d", engine.config.Nodes)
+	}
+
+	return limits
+}
+
+func (uxi *UXI) BestMove(engine *Engine, timeout time.Duration) (string, error) {
+	line, err := engine.Await("^bestmove", timeout)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("engine: invalid bestmove %q", line)
+	}
+
+	// The move the engine expects in reply, which it can ponder on.
+	if len(fields) >= 4 && fields[2] == "ponder" {
+		engine.ponderMove = fields[3]
+	}
+
+	return fields[1], nil
+}
+
+func (uxi *UXI) Quit(engine *Engine) error {
+	return engine.Write("quit")
+}
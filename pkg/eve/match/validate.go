@@ -0,0 +1,169 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
+)
+
+// BookSummary summarises the openings of an opening book.
+type BookSummary struct {
+	// Openings is the number of valid and unique openings in the book, and
+	// Duplicates is the number of duplicate openings removed from it.
+	Openings   int
+	Duplicates int
+
+	// Invalid stores the errors in the book's invalid openings.
+	Invalid []BookError
+
+	// SideToMove stores the number of openings with each side to move.
+	SideToMove [2]int
+}
+
+// BookError is an error in an opening of an opening book.
+type BookError struct {
+	// Line is the line of the book on which the opening starts.
+	Line int
+	Err  error
+}
+
+func (err BookError) Error() string {
+	return fmt.Sprintf("line %d: %v", err.Line, err.Err)
+}
+
+func (err BookError) Unwrap() error {
+	return err.Err
+}
+
+// CheckBook validates the epd or pgn opening book with the given config
+// with the oracle of the given game, and returns a summary of it.
+func CheckBook(game string, config OpeningConfig) (BookSummary, error) {
+	if config.format() == "polyglot" {
+		return BookSummary{}, fmt.Errorf("check book: polyglot books can't be checked")
+	}
+
+	entries, err := readBook(config)
+	if err != nil {
+		return BookSummary{}, err
+	}
+
+	_, summary := validateBook(game, entries)
+	return summary, nil
+}
+
+// bookEntry is an opening read from an opening book.
+type bookEntry struct {
+	Opening
+
+	// line is the line of the book on which the opening starts, and err is
+	// the error encountered while reading the opening, if any.
+	line int
+	err  error
+}
+
+// readBook reads the openings of the epd or pgn book with the given config.
+func readBook(config OpeningConfig) ([]bookEntry, error) {
+	switch format := config.format(); format {
+	case "epd":
+		return readEPDBook(config.File)
+	case "pgn":
+		return readPGNBook(config.File)
+	default:
+		return nil, fmt.Errorf("read book: unknown format %s", format)
+	}
+}
+
+// ceRegexp matches the centipawn evaluation operation of an EPD.
+var ceRegexp = regexp.MustCompile(`(?:^|[\s;])ce\s+([+-]?\d+)\s*(?:;|$)`)
+
+// readEPDBook reads the openings of the epd book at the given path, which
+// are situated on separate lines. Blank lines are ignored.
+func readEPDBook(path string) ([]bookEntry, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []bookEntry
+	for i, line := range strings.Split(string(file), "\n") {
+		line = strings.Trim(line, "\n\r\t ")
+		if line == "" {
+			continue
+		}
+
+		entry := bookEntry{line: i + 1}
+		entry.FEN = line
+
+		// The ce operation of an EPD is the position's evaluation.
+		if match := ceRegexp.FindStringSubmatch(line); match != nil {
+			entry.Eval, _ = strconv.Atoi(match[1])
+			entry.HasEval = true
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// validateBook validates the given openings with the oracle of the given
+// game, which also removes any EPD operations from them, and returns the
+// valid and unique openings along with a summary of the book.
+func validateBook(game string, entries []bookEntry) ([]Opening, BookSummary) {
+	var summary BookSummary
+	var openings []Opening
+
+	oracle := games.GetOracle(game)
+	validator, _ := oracle.(games.Validator)
+
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if entry.err == nil && validator != nil {
+			entry.FEN, entry.err = validator.Validate(entry.FEN)
+		}
+
+		if entry.err != nil {
+			summary.Invalid = append(summary.Invalid, BookError{entry.line, entry.err})
+			continue
+		}
+
+		key := entry.Position.String()
+		if seen[key] {
+			summary.Duplicates++
+			continue
+		}
+
+		seen[key] = true
+
+		openings = append(openings, entry.Opening)
+
+		if oracle != nil {
+			oracle.Initialize(entry.FEN)
+			for _, move := range entry.Moves {
+				_ = oracle.MakeMove(move)
+			}
+
+			summary.SideToMove[oracle.SideToMove()]++
+		}
+	}
+
+	summary.Openings = len(openings)
+	return openings, summary
+}
@@ -0,0 +1,383 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// XBoard implements the Chess Engine Communication Protocol, also known as
+// the xboard or winboard protocol. Only engines which support version 2 of
+// the protocol and the setboard feature are supported.
+//
+// Unlike UXI protocols, the engine keeps track of the game by itself, so the
+// engine is kept in force mode while it isn't thinking, and only the moves
+// it doesn't already know about are sent to it. Engines which are allowed to
+// ponder are instead left in play mode after they move, so that they can
+// ponder during their opponent's turn.
+type XBoard struct {
+	features map[string]string
+
+	ping int
+
+	// The position known by the engine.
+	fen   string
+	moves []string
+
+	// playing is set while the engine isn't in force mode, and pending is
+	// the opponent's move which will be sent to a playing engine when its
+	// clock is started.
+	playing bool
+	pending string
+
+	// last is the command which made the engine start thinking, which is
+	// the only one whose errors are about the engine's move.
+	last string
+}
+
+var ErrNoPonder = errors.New("xboard: engines ponder by themselves")
+
+var ErrNoSetboard = errors.New("xboard: engine doesn't support setboard")
+
+var ErrNoNodes = errors.New("xboard: node limits aren't supported")
+
+// ErrEngineError is returned when the engine rejects the move or command
+// which made it start thinking.
+var ErrEngineError = errors.New("xboard: engine reported an error")
+
+// featureRegexp matches a single name=value pair in a feature command.
+var featureRegexp = regexp.MustCompile(`(\w+)=("[^"]*"|\S+)`)
+
+func (xboard *XBoard) Initialize(engine *Engine) error {
+	// The protocol has no way to limit the number of nodes searched.
+	if engine.config.Nodes > 0 {
+		return ErrNoNodes
+	}
+
+	if err := engine.Write("xboard"); err != nil {
+		return err
+	}
+
+	if err := engine.Write("protover 2"); err != nil {
+		return err
+	}
+
+	// Engines have two seconds to send all their features, unless they ask
+	// for more time with done=0, in which case they get an hour.
+	xboard.features = map[string]string{}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		line, err := engine.Await("^feature ", time.Until(deadline))
+		if err != nil {
+			if errors.Is(err, ErrReadTimeout) {
+				break
+			}
+
+			return err
+		}
+
+		done := false
+		for _, feature := range featureRegexp.FindAllStringSubmatch(line, -1) {
+			name, value := feature[1], strings.Trim(feature[2], `"`)
+
+			// Arbiter parses moves in coordinate notation only.
+			if name == "san" && value == "1" {
+				if err := engine.Write("rejected san"); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			xboard.features[name] = value
+			if name == "myname" {
+				engine.id = value
+			}
+
+			if err := engine.Write("accepted %s", name); err != nil {
+				return err
+			}
+
+			if name == "done" {
+				switch value {
+				case "0":
+					deadline = time.Now().Add(time.Hour)
+				case "1":
+					done = true
+				}
+			}
+		}
+
+		if done {
+			break
+		}
+	}
+
+	if xboard.features["setboard"] != "1" {
+		return ErrNoSetboard
+	}
+
+	// Engines ponder by themselves in this protocol, so pondering is just
+	// turned on or off. The engine is only told about the opponent's move
+	// when its clock starts, so it can ponder until then.
+	ponder := "easy"
+	if engine.config.Ponder {
+		ponder = "hard"
+	}
+
+	if err := engine.Write(ponder); err != nil {
+		return err
+	}
+
+	// Set the options in a sorted order so that the commands sent to the
+	// engine are the same every time it is initialized.
+	names := make([]string, 0, len(engine.config.Options))
+	for name := range engine.config.Options {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		value := engine.config.Options[name]
+		if err := engine.Write("option %s=%s", name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (xboard *XBoard) NewGame(engine *Engine) error {
+	if err := engine.Write("new"); err != nil {
+		return err
+	}
+
+	if err := engine.Write("force"); err != nil {
+		return err
+	}
+
+	// The position known by the engine isn't relevant anymore.
+	xboard.fen, xboard.moves = "", nil
+	xboard.playing, xboard.pending = false, ""
+
+	tc, err := ParseTime(engine.config.TimeC)
+	if err != nil {
+		return err
+	}
+
+	if tc.MoveTime > 0 {
+		err = engine.Write("st %g", tc.MoveTime.Seconds())
+	} else {
+		// A movestogo of 0 means that the time control isn't cyclic.
+		movesToGo := tc.MovesToGo
+		if movesToGo < 0 {
+			movesToGo = 0
+		}
+
+		// The base time can only be given in whole seconds, so it is rounded,
+		// but not down to zero. The engine is sent its exact clock with the
+		// time command before each move anyway.
+		base := int(math.Round(tc.Base.Seconds()))
+		if base == 0 && tc.Base > 0 {
+			base = 1
+		}
+
+		err = engine.Write(
+			"level %d %d:%02d %g",
+			movesToGo,
+			base/60, base%60,
+			tc.Inc.Seconds(),
+		)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// The depth limit is removed by the new command, so it is set each game.
+	if engine.config.Depth > 0 {
+		if err := engine.Write("sd %d", engine.config.Depth); err != nil {
+			return err
+		}
+	}
+
+	return xboard.Synchronize(engine)
+}
+
+// drainQuiet is the time for which an engine which doesn't support ping has
+// to stay silent before it is assumed to have answered every command.
+const drainQuiet = 50 * time.Millisecond
+
+func (xboard *XBoard) Synchronize(engine *Engine) error {
+	// Engines which don't support ping can't be synchronized with, so their
+	// pending output, like errors about unknown commands or result claims
+	// after their last move, is discarded instead.
+	if xboard.features["ping"] != "1" {
+		return engine.Drain(drainQuiet)
+	}
+
+	xboard.ping++
+	if err := engine.Write("ping %d", xboard.ping); err != nil {
+		return err
+	}
+
+	_, err := engine.Await(fmt.Sprintf("^pong %d$", xboard.ping), 5*time.Second)
+	return err
+}
+
+func (xboard *XBoard) Position(engine *Engine, position Position) error {
+	known := position.FEN == xboard.fen && len(position.Moves) >= len(xboard.moves) &&
+		slices.Equal(position.Moves[:len(xboard.moves)], xboard.moves)
+
+	// A pondering engine is only sent its opponent's move when its clock is
+	// started, since forcing it would stop it from pondering.
+	if known && xboard.playing && engine.config.Ponder &&
+		len(position.Moves) == len(xboard.moves)+1 {
+		xboard.pending = position.Moves[len(xboard.moves)]
+		xboard.moves = slices.Clone(position.Moves)
+		return nil
+	}
+
+	// Stop the engine from thinking about the position by itself.
+	if err := engine.Write("force"); err != nil {
+		return err
+	}
+
+	xboard.playing, xboard.pending = false, ""
+
+	// Send only the new moves if the engine knows the position's history,
+	// otherwise set the position up from scratch.
+	pending := position.Moves
+	if known {
+		pending = position.Moves[len(xboard.moves):]
+	} else if err := engine.Write("setboard %s", position.FEN); err != nil {
+		return err
+	}
+
+	for _, move := range pending {
+		if err := xboard.userMove(engine, move); err != nil {
+			return err
+		}
+	}
+
+	xboard.fen = position.FEN
+	xboard.moves = slices.Clone(position.Moves)
+	return nil
+}
+
+func (xboard *XBoard) Go(engine *Engine, clock Clock) error {
+	// The clocks are specified in centiseconds.
+	if err := engine.Write("time %d", clock.Us().Base.Milliseconds()/10); err != nil {
+		return err
+	}
+
+	if err := engine.Write("otim %d", clock.Them().Base.Milliseconds()/10); err != nil {
+		return err
+	}
+
+	// An engine in play mode starts thinking when it receives a move.
+	if xboard.pending != "" {
+		move := xboard.pending
+		xboard.pending = ""
+		return xboard.userMove(engine, move)
+	}
+
+	xboard.playing, xboard.last = true, "go"
+	return engine.Write("go")
+}
+
+func (xboard *XBoard) Ponder(engine *Engine, position Position, clock Clock) error {
+	return ErrNoPonder
+}
+
+func (xboard *XBoard) PonderHit(engine *Engine) error {
+	return ErrNoPonder
+}
+
+func (xboard *XBoard) Stop(engine *Engine) error {
+	// The move now command makes the engine stop thinking and move.
+	return engine.Write("?")
+}
+
+func (xboard *XBoard) GameOver(engine *Engine, result Result) error {
+	// The engine is reset with the new command before its next game, and
+	// arbiter adjudicates games by itself, so the result isn't sent.
+	return nil
+}
+
+func (xboard *XBoard) BestMove(engine *Engine, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		line, err := engine.Await(`^(move |resign|1-0|0-1|1/2-1/2|Illegal move|Error)`, time.Until(deadline))
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case strings.HasPrefix(line, "move "):
+			move := strings.TrimSpace(strings.TrimPrefix(line, "move "))
+			xboard.moves = append(xboard.moves, move)
+			return move, nil
+
+		case line == "resign":
+			return "", ErrResigned
+
+		case strings.HasPrefix(line, "Illegal move"), strings.HasPrefix(line, "Error"):
+			// Errors about other commands, like the time commands which
+			// the engine may not know, have nothing to do with its move.
+			if !xboard.answers(line) {
+				continue
+			}
+
+			return "", fmt.Errorf("%w: %s", ErrEngineError, line)
+
+		default:
+			return "", fmt.Errorf("%w: %s", ErrFalseClaim, line)
+		}
+	}
+}
+
+// answers checks if the given error reported by the engine, which has the
+// form "Error (reason): command", is about the command which made the
+// engine start thinking.
+func (xboard *XBoard) answers(line string) bool {
+	_, command, found := strings.Cut(line, ": ")
+	if !found {
+		return false
+	}
+
+	command = strings.TrimSpace(command)
+	return command == xboard.last || command == strings.TrimPrefix(xboard.last, "usermove ")
+}
+
+func (xboard *XBoard) Quit(engine *Engine) error {
+	return engine.Write("quit")
+}
+
+// userMove sends the given move to the engine in the format it asked for.
+func (xboard *XBoard) userMove(engine *Engine, move string) error {
+	xboard.last = move
+	if xboard.features["usermove"] == "1" {
+		xboard.last = "usermove " + move
+	}
+
+	return engine.Write("%s", xboard.last)
+}
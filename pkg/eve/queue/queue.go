@@ -0,0 +1,334 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package queue implements a persistent queue of tests, which are run one
+// after another or at the same time, sharing a pool of workers.
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	arbiter "laptudirm.com/x/arbiter/pkg/common"
+	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+)
+
+// Directory is the directory the queue's status and the configs of its
+// tests are stored in.
+var Directory = filepath.Join(arbiter.Directory, "queue")
+
+// ErrNoTest is returned when a test is not in the queue.
+var ErrNoTest = errors.New("queue: no such test")
+
+// Status is the status of a queued test.
+type Status string
+
+const (
+	Queued   Status = "queued"
+	Running  Status = "running"
+	Finished Status = "finished"
+	Failed   Status = "failed"
+)
+
+// Entry is a test in the queue.
+type Entry struct {
+	Name string `yaml:"name"`
+
+	// Tests with a higher priority are run before, and get more workers
+	// than, the ones with a lower priority.
+	Priority int    `yaml:"priority"`
+	Status   Status `yaml:"status"`
+
+	Added    time.Time `yaml:"added"`
+	Started  time.Time `yaml:"started,omitempty"`
+	Finished time.Time `yaml:"finished,omitempty"`
+
+	// Conclusion is the hypothesis accepted by a finished test, and Error
+	// the reason a failed test failed.
+	Conclusion string `yaml:"conclusion,omitempty"`
+	Error      string `yaml:"error,omitempty"`
+}
+
+// mu serializes the updates to the queue made by this process, and the
+// lock file those made by different processes.
+var mu sync.Mutex
+
+// staleLock is the age after which a lock file is assumed to have been left
+// behind by a process which was killed while updating the queue.
+const staleLock = 10 * time.Second
+
+// statusFile returns the path of the file the queue's status is stored in.
+func statusFile() string {
+	return filepath.Join(Directory, "status.yaml")
+}
+
+// configFile returns the path of the file the config of the test with the
+// given name is stored in.
+func configFile(name string) string {
+	return filepath.Join(Directory, name+".yaml")
+}
+
+// lockFile returns the path of the file which exists while a process is
+// updating the queue's status.
+func lockFile() string {
+	return filepath.Join(Directory, "status.lock")
+}
+
+// lock waits for the queue's lock file to be free and takes it, and returns
+// a function which frees it. The file is created exclusively, which works
+// the same way on every platform.
+func lock() (func(), error) {
+	for {
+		file, err := os.OpenFile(lockFile(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { _ = os.Remove(lockFile()) }, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockFile()); err == nil && time.Since(info.ModTime()) > staleLock {
+			_ = os.Remove(lockFile())
+			continue
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// List returns the tests in the queue, in the order they were added.
+func List() ([]Entry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return load()
+}
+
+// load reads the queue's status from its file.
+func load() ([]Entry, error) {
+	data, err := os.ReadFile(statusFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("queue: %w", err)
+	}
+
+	return entries, nil
+}
+
+// update applies the given change to the queue's status, and stores it.
+// The status is read before every change, so that tests can be added to the
+// queue while it is being run by another process.
+func update(change func(entries []Entry) ([]Entry, error)) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	unlock, err := lock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+
+	if entries, err = change(entries); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	// The status is replaced at once, so that it is never read half written.
+	temp := statusFile() + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, statusFile())
+}
+
+// Add adds the test with the given config to the queue with the given
+// priority. Tests without a name are named by the time they were added,
+// and tests which can't be started aren't added.
+func Add(config sprt.Config, priority int) (Entry, error) {
+	if config.Name == "" {
+		config.Name = time.Now().Format("sprt-20060102-150405")
+	}
+
+	if _, err := sprt.NewTournament(config); err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{
+		Name:     config.Name,
+		Priority: priority,
+		Status:   Queued,
+		Added:    time.Now(),
+	}
+
+	err := update(func(entries []Entry) ([]Entry, error) {
+		for _, other := range entries {
+			if other.Name == entry.Name {
+				return nil, fmt.Errorf("queue: test %s already queued", entry.Name)
+			}
+		}
+
+		// The config is copied, so that the test isn't changed if its file
+		// is changed while it is queued.
+		data, err := yaml.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(configFile(entry.Name), data, 0644); err != nil {
+			return nil, err
+		}
+
+		return append(entries, entry), nil
+	})
+
+	return entry, err
+}
+
+// Remove removes the test with the given name from the queue.
+func Remove(name string) error {
+	return update(func(entries []Entry) ([]Entry, error) {
+		for i, entry := range entries {
+			if entry.Name != name {
+				continue
+			}
+
+			if entry.Status == Running {
+				return nil, fmt.Errorf("queue: test %s is running", name)
+			}
+
+			_ = os.Remove(configFile(name))
+			return append(entries[:i], entries[i+1:]...), nil
+		}
+
+		return nil, ErrNoTest
+	})
+}
+
+// Config returns the config of the given queued test. Tests which were
+// started before are continued from their last checkpoint.
+func Config(entry Entry) (sprt.Config, error) {
+	path := configFile(entry.Name)
+	if !entry.Started.IsZero() {
+		checkpoint := filepath.Join(arbiter.Directory, "paused", "sprt", entry.Name)
+		if _, err := os.Stat(checkpoint); err == nil {
+			path = checkpoint
+		}
+	}
+
+	var config sprt.Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	return config, yaml.Unmarshal(data, &config)
+}
+
+// next marks the queued test which should be run next as running, and
+// returns it. Tests with higher priorities are run first, and tests with
+// the same priority in the order they were added.
+func next() (Entry, bool, error) {
+	var found Entry
+	ok := false
+
+	err := update(func(entries []Entry) ([]Entry, error) {
+		queued := []int{}
+		for i, entry := range entries {
+			if entry.Status == Queued {
+				queued = append(queued, i)
+			}
+		}
+
+		if len(queued) == 0 {
+			return entries, nil
+		}
+
+		sort.SliceStable(queued, func(i, j int) bool {
+			return entries[queued[i]].Priority > entries[queued[j]].Priority
+		})
+
+		// The returned entry is only marked as started if it was started
+		// before, so that it is continued from its checkpoint.
+		entry := &entries[queued[0]]
+		found, ok = *entry, true
+
+		entry.Status = Running
+		if entry.Started.IsZero() {
+			entry.Started = time.Now()
+		}
+
+		return entries, nil
+	})
+
+	return found, ok, err
+}
+
+// finish marks the test with the given name as finished with the given
+// conclusion, or as failed if the given error isn't nil.
+func finish(name, conclusion string, err error) error {
+	return update(func(entries []Entry) ([]Entry, error) {
+		for i := range entries {
+			entry := &entries[i]
+			if entry.Name != name {
+				continue
+			}
+
+			entry.Status, entry.Finished = Finished, time.Now()
+			entry.Conclusion = conclusion
+			if err != nil {
+				entry.Status, entry.Error = Failed, err.Error()
+			}
+		}
+
+		return entries, nil
+	})
+}
+
+// requeue marks the tests which were left running, by a runner which was
+// stopped, as queued again, so that they are continued.
+func requeue() error {
+	return update(func(entries []Entry) ([]Entry, error) {
+		for i := range entries {
+			if entries[i].Status == Running {
+				entries[i].Status = Queued
+			}
+		}
+
+		return entries, nil
+	})
+}
@@ -0,0 +1,224 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
+)
+
+// Runner runs the tests in the queue.
+type Runner struct {
+	// Tests is the number of tests run at the same time.
+	Tests int
+
+	// Workers is the number of game pairs played at once by all the running
+	// tests together. If it is zero, each test plays as many pairs at once
+	// as its own config's concurrency instead.
+	Workers int
+
+	// Store is the database the tests' games are stored in, if any.
+	Store *store.Store
+
+	// Poll is the interval at which the queue is checked for new tests.
+	Poll time.Duration
+}
+
+// Run runs the tests in the queue as they are added, and never returns
+// unless the queue's status can't be updated. Tests left running by a
+// previous runner are continued from their last checkpoint.
+func (runner *Runner) Run() error {
+	if err := requeue(); err != nil {
+		return err
+	}
+
+	var pool *Pool
+	if runner.Workers > 0 {
+		pool = NewPool(runner.Workers)
+	}
+
+	// slots has a token for every test which can be started.
+	tests := runner.Tests
+	if tests < 1 {
+		tests = 1
+	}
+
+	slots := make(chan struct{}, tests)
+	for i := 0; i < tests; i++ {
+		slots <- struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		<-slots
+
+		entry, found, err := next()
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			slots <- struct{}{}
+			time.Sleep(runner.Poll)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { slots <- struct{}{} }()
+
+			conclusion, err := runner.run(entry, pool)
+			if err != nil {
+				logrus.Errorf("queue: test %s failed: %v", entry.Name, err)
+			} else {
+				logrus.Infof("queue: test %s finished %s", entry.Name, conclusion)
+			}
+
+			if err := finish(entry.Name, conclusion, err); err != nil {
+				logrus.Errorf("queue: %v", err)
+			}
+		}()
+	}
+}
+
+// run runs the given test, with its workers taken from the given pool if
+// it isn't nil, and returns its conclusion.
+func (runner *Runner) run(entry Entry, pool *Pool) (string, error) {
+	config, err := Config(entry)
+	if err != nil {
+		return "", err
+	}
+
+	if pool != nil {
+		// The test can use the whole pool when no other test is running.
+		config.Concurrency = pool.Size()
+	}
+
+	test, err := sprt.NewTournament(config)
+	if err != nil {
+		return "", err
+	}
+
+	test.Store = runner.Store
+	if pool != nil {
+		test.Slots = pool.For(entry.Priority)
+	}
+
+	// Tests which were interrupted are rebuilt from their stored games,
+	// which may be ahead of their last checkpoint.
+	if runner.Store != nil && !entry.Started.IsZero() {
+		games, err := runner.Store.Games(store.Run(test.Name))
+		if err != nil {
+			return "", err
+		}
+
+		if len(games) > 0 {
+			test.Restore(games)
+		}
+	}
+
+	logrus.Infof("queue: starting test %s", entry.Name)
+	if err := test.Start(); err != nil {
+		return "", err
+	}
+
+	conclusion := test.Conclusion
+	if conclusion == "" {
+		conclusion = "match"
+	}
+
+	return conclusion, nil
+}
+
+// Pool is a pool of workers shared by tests, which are given to the tests
+// with the highest priority first.
+type Pool struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	size, free int
+
+	// waiting stores the number of threads waiting for a worker with each
+	// priority.
+	waiting map[int]int
+}
+
+// NewPool returns a pool with the given number of workers.
+func NewPool(size int) *Pool {
+	pool := &Pool{size: size, free: size, waiting: map[int]int{}}
+	pool.cond = sync.NewCond(&pool.mu)
+	return pool
+}
+
+// Size returns the number of workers in the pool.
+func (pool *Pool) Size() int {
+	return pool.size
+}
+
+// For returns the slots of a test with the given priority in the pool.
+func (pool *Pool) For(priority int) sprt.Slots {
+	return slots{pool, priority}
+}
+
+// acquire waits for a free worker, which is given to the waiting threads
+// with the highest priority first, and takes it.
+func (pool *Pool) acquire(priority int) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.waiting[priority]++
+	for pool.free == 0 || pool.preempted(priority) {
+		pool.cond.Wait()
+	}
+
+	pool.waiting[priority]--
+	pool.free--
+}
+
+// preempted reports if a thread with a higher priority than the given one
+// is waiting for a worker.
+func (pool *Pool) preempted(priority int) bool {
+	for other, count := range pool.waiting {
+		if other > priority && count > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// release frees a worker.
+func (pool *Pool) release() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.free++
+	pool.cond.Broadcast()
+}
+
+// slots are the slots of a test with a priority in a pool.
+type slots struct {
+	pool     *Pool
+	priority int
+}
+
+func (slots slots) Acquire() { slots.pool.acquire(slots.priority) }
+func (slots slots) Release() { slots.pool.release() }
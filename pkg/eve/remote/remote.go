@@ -0,0 +1,68 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remote implements the distribution of the games of a test to
+// workers on other machines, over HTTP. The server leases batches of game
+// pairs to the workers, which play them with their own engines and report
+// their outcomes, which are added to the test as if it had played them.
+package remote
+
+import (
+	"time"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match"
+	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+)
+
+// BatchRequest is sent by a worker to request a batch of game pairs.
+type BatchRequest struct {
+	// Worker is the name of the worker, and Pairs the maximum number of
+	// pairs it wants, usually the number of pairs it can play at once.
+	Worker string
+	Pairs  int
+}
+
+// Batch is a batch of game pairs leased to a worker.
+type Batch struct {
+	Pairs []Pair
+
+	// Deadline is the time by which the pairs' results must be reported,
+	// after which they may be given to another worker.
+	Deadline time.Time
+}
+
+// Pair is a pair of games played with the same opening.
+type Pair struct {
+	Number int
+	Games  [2]sprt.Match
+}
+
+// Report is sent by a worker with the outcomes of the pairs it played.
+type Report struct {
+	Worker string
+	Pairs  []PairReport
+}
+
+// PairReport stores the outcomes of the games of a pair.
+type PairReport struct {
+	Number int
+	Games  [2]GameReport
+}
+
+// GameReport stores the outcome of a game, and the times at which it was
+// started and finished.
+type GameReport struct {
+	Outcome match.Outcome
+
+	Started, Finished time.Time
+}
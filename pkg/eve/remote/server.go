@@ -0,0 +1,221 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"laptudirm.com/x/arbiter/pkg/eve/match"
+	"laptudirm.com/x/arbiter/pkg/eve/sprt"
+)
+
+// Server leases the game pairs of a test to workers, and adds the results
+// they report to the test. The test is run with its Start method as usual,
+// and may play pairs with its own threads at the same time.
+//
+// Workers request batches with a POST to /batch, which is answered with
+// 204 No Content if no pairs are available at the moment, and with 410 Gone
+// once the test has ended. Their results are reported with a POST to
+// /results.
+type Server struct {
+	Test *sprt.SPRT
+
+	// Batch is the maximum number of pairs leased to a worker at once, and
+	// Lease is the time the worker has to report their results, after which
+	// they are leased to other workers.
+	Batch int
+	Lease time.Duration
+
+	mu     sync.Mutex
+	leases map[int]*lease
+}
+
+// lease is a game pair leased to a worker.
+type lease struct {
+	pair     Pair
+	worker   string
+	deadline time.Time
+}
+
+// NewServer returns a server for the given test, which leases the given
+// number of pairs at once for the given time.
+func NewServer(test *sprt.SPRT, batch int, duration time.Duration) *Server {
+	return &Server{
+		Test:  test,
+		Batch: batch,
+		Lease: duration,
+
+		leases: map[int]*lease{},
+	}
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/batch":
+		server.batch(w, r)
+	case "/results":
+		server.results(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// batch leases a batch of game pairs to the requesting worker.
+func (server *Server) batch(w http.ResponseWriter, r *http.Request) {
+	var request BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case <-server.Test.Done():
+		w.WriteHeader(http.StatusGone)
+		return
+	default:
+	}
+
+	size := server.Batch
+	if request.Pairs > 0 && request.Pairs < size {
+		size = request.Pairs
+	}
+
+	batch := Batch{Deadline: time.Now().Add(server.Lease)}
+
+	server.mu.Lock()
+
+	// Pairs whose leases have expired are given out again before new ones,
+	// so that a worker which stopped doesn't hold up the test.
+	expired := []int{}
+	for number, lease := range server.leases {
+		if lease.deadline.Before(time.Now()) {
+			expired = append(expired, number)
+		}
+	}
+
+	sort.Ints(expired)
+	for _, number := range expired {
+		if len(batch.Pairs) == size {
+			break
+		}
+
+		lease := server.leases[number]
+		logrus.Warnf("remote: lease of pair %d to %s expired, leasing it to %s", number, lease.worker, request.Worker)
+
+		lease.worker, lease.deadline = request.Worker, batch.Deadline
+		batch.Pairs = append(batch.Pairs, lease.pair)
+	}
+
+	// Only the games of newly issued pairs are started, since the games of
+	// the expired leases were started when they were first issued.
+	issued := len(batch.Pairs)
+	for len(batch.Pairs) < size {
+		number, opening, ok := server.Test.NextPair()
+		if !ok {
+			break
+		}
+
+		pair := Pair{Number: number, Games: server.Test.PairMatches(number, opening)}
+		server.leases[number] = &lease{
+			pair:     pair,
+			worker:   request.Worker,
+			deadline: batch.Deadline,
+		}
+
+		batch.Pairs = append(batch.Pairs, pair)
+	}
+
+	server.mu.Unlock()
+
+	if len(batch.Pairs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for i := issued; i < len(batch.Pairs); i++ {
+		for game := range batch.Pairs[i].Games {
+			server.Test.StartGame(&batch.Pairs[i].Games[game])
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(batch)
+}
+
+// results adds the results of the game pairs reported by a worker to the
+// test. Results of pairs which aren't leased to the worker, because their
+// leases expired and they were leased to other workers, or they have
+// already been reported, are ignored.
+func (server *Server) results(w http.ResponseWriter, r *http.Request) {
+	var report Report
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, reported := range report.Pairs {
+		for _, game := range reported.Games {
+			if game.Outcome.White != 0 && game.Outcome.White != 1 {
+				http.Error(w, "invalid white engine", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	for _, reported := range report.Pairs {
+		server.mu.Lock()
+		lease, found := server.leases[reported.Number]
+		leased := found && lease.worker == report.Worker
+		if leased {
+			delete(server.leases, reported.Number)
+		}
+
+		server.mu.Unlock()
+
+		if !leased {
+			logrus.Warnf("remote: ignoring result of pair %d from %s, which isn't leased to it", reported.Number, report.Worker)
+			continue
+		}
+
+		var pair sprt.PairResult
+		for game, result := range reported.Games {
+			pair.Matches[game] = server.Test.EndGame(
+				&lease.pair.Games[game], result.Outcome,
+				result.Started, result.Finished,
+			)
+		}
+
+		pair.Result = match.GetPairResult(
+			pair.Matches[0].Result,
+			pair.Matches[1].Result,
+		)
+
+		if !server.Test.Submit(pair) {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
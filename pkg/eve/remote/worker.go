@@ -0,0 +1,224 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"laptudirm.com/x/arbiter/pkg/eve/match"
+)
+
+// Worker plays the game pairs leased to it by a server.
+type Worker struct {
+	// URL is the address of the server, and Name the name the worker
+	// identifies itself with.
+	URL  string
+	Name string
+
+	// Concurrency is the number of pairs played at once.
+	Concurrency int
+
+	// Engines maps the names of engines to the commands they are run with
+	// on the worker's machine. The server isn't trusted to choose the
+	// commands, so the worker refuses to play engines which aren't in it.
+	Engines map[string]string
+
+	// Poll is the interval at which the server is asked for pairs when it
+	// has none to give out.
+	Poll time.Duration
+
+	client http.Client
+}
+
+// Run plays the pairs leased by the server until its test ends.
+func (worker *Worker) Run() error {
+	concurrency := worker.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	pairs := make(chan Pair)
+	reports := make(chan PairReport)
+	defer close(pairs)
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			// Engines are reused between the games played by a thread.
+			pool := match.NewPool(2)
+			defer pool.Close()
+
+			for pair := range pairs {
+				reports <- worker.play(pair, pool)
+			}
+		}()
+	}
+
+	for {
+		var batch Batch
+		status, err := worker.post("/batch", BatchRequest{Worker: worker.Name, Pairs: concurrency}, &batch)
+		if err != nil {
+			return err
+		}
+
+		switch status {
+		case http.StatusGone:
+			logrus.Info("remote: test ended")
+			return nil
+		case http.StatusNoContent:
+			time.Sleep(worker.Poll)
+			continue
+		}
+
+		logrus.Infof("remote: leased %d pairs until %s", len(batch.Pairs), batch.Deadline.Format(time.TimeOnly))
+
+		// The pairs are left to expire if they can't be played, so that the
+		// server gives them to other workers.
+		for i := range batch.Pairs {
+			for game := range batch.Pairs[i].Games {
+				engines := &batch.Pairs[i].Games[game].Engines
+				for j := range engines {
+					if err := worker.localize(&engines[j]); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		go func() {
+			for _, pair := range batch.Pairs {
+				pairs <- pair
+			}
+		}()
+
+		report := Report{Worker: worker.Name}
+		for range batch.Pairs {
+			report.Pairs = append(report.Pairs, <-reports)
+		}
+
+		if status, err = worker.post("/results", report, nil); err != nil {
+			return err
+		}
+
+		if status == http.StatusGone {
+			logrus.Info("remote: test ended")
+			return nil
+		}
+	}
+}
+
+// play plays the games of the given pair with engines from the given pool.
+func (worker *Worker) play(pair Pair, pool *match.Pool) PairReport {
+	report := PairReport{Number: pair.Number}
+	for game := range pair.Games {
+		config := pair.Games[game].Config
+
+		logrus.Infof(
+			"\x1b[33mStarting\x1b[0m Game #%d: %s vs %s\n",
+			pair.Games[game].Number, config.Engines[0].Name, config.Engines[1].Name,
+		)
+
+		started := time.Now()
+		outcome := match.Run(&config, pool)
+		report.Games[game] = GameReport{
+			Outcome:  outcome,
+			Started:  started,
+			Finished: time.Now(),
+		}
+
+		logrus.Infof(
+			"\x1b[32mFinished\x1b[0m Game #%d: %s vs %s: %s %s\n",
+			pair.Games[game].Number, config.Engines[0].Name, config.Engines[1].Name,
+			outcome.Result, outcome.Reason,
+		)
+	}
+
+	return report
+}
+
+// localize changes the given engine config from the server's to run the
+// engine installed on the worker's machine. The server isn't trusted, so
+// the parts of the config which could make the worker run commands, or the
+// engine read and write files, are dropped.
+func (worker *Worker) localize(engine *match.EngineConfig) error {
+	cmd, found := worker.Engines[engine.Name]
+	if !found {
+		return fmt.Errorf("remote: engine %s is not installed on this worker", engine.Name)
+	}
+
+	engine.Cmd, engine.Dir, engine.Arg = cmd, "", ""
+	engine.InitStr = ""
+
+	// The engine's logs are kept on the server's machine, which the worker
+	// can't write to.
+	engine.Stderr, engine.Transcript = "", false
+
+	for name, value := range engine.Options {
+		if !safeOption(value) {
+			logrus.Warnf("remote: ignoring option %s of engine %s", name, engine.Name)
+			delete(engine.Options, name)
+		}
+	}
+
+	return nil
+}
+
+// safeOption reports if an engine option can be set to the given value on
+// the server's request. Only numeric and boolean values can be, since other
+// values may be the paths of files the engine reads or writes.
+func safeOption(value string) bool {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+
+	return value == "true" || value == "false"
+}
+
+// post sends the given request to the given endpoint of the server as JSON,
+// decodes its response into the given value, if any, and returns the
+// response's status.
+func (worker *Worker) post(endpoint string, request, response any) (int, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+
+	url := strings.TrimSuffix(worker.URL, "/") + endpoint
+	resp, err := worker.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("remote: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if response != nil {
+			if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+				return 0, fmt.Errorf("remote: %w", err)
+			}
+		}
+	case http.StatusNoContent, http.StatusGone:
+	default:
+		return 0, fmt.Errorf("remote: %s %s", endpoint, resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
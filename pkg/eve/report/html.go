@@ -0,0 +1,170 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+)
+
+// WriteHTML writes the report as a self-contained HTML page.
+func (report Report) WriteHTML(w io.Writer) error {
+	return page.Execute(w, report)
+}
+
+// Chart returns an inline SVG chart of the LLR of an SPRT after each game
+// pair, along with its stopping bounds.
+func (report Report) Chart() template.HTML {
+	if len(report.Trajectory) == 0 || report.Run.SPRT == nil {
+		return ""
+	}
+
+	const width, height, pad = 640.0, 240.0, 24.0
+
+	lower, upper := report.Run.SPRT.Lower, report.Run.SPRT.Upper
+	low, high := lower, upper
+	for _, point := range report.Trajectory {
+		low, high = math.Min(low, point.LLR), math.Max(high, point.LLR)
+	}
+
+	pairs := float64(report.Trajectory[len(report.Trajectory)-1].Pairs)
+	x := func(n float64) float64 { return pad + (width-2*pad)*n/math.Max(pairs, 1) }
+	y := func(llr float64) float64 { return pad + (height-2*pad)*(high-llr)/math.Max(high-low, 1e-9) }
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`, width, height, width, height)
+
+	for _, bound := range []struct {
+		llr   float64
+		color string
+	}{{lower, "#c0392b"}, {0, "#999"}, {upper, "#27ae60"}} {
+		fmt.Fprintf(&svg,
+			`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-dasharray="4 4"/>`,
+			pad, y(bound.llr), width-pad, y(bound.llr), bound.color,
+		)
+		fmt.Fprintf(&svg,
+			`<text x="%.1f" y="%.1f" font-size="10" fill="%s">%.2f</text>`,
+			2.0, y(bound.llr)+3, bound.color, bound.llr,
+		)
+	}
+
+	points := make([]string, 0, len(report.Trajectory)+1)
+	points = append(points, fmt.Sprintf("%.1f,%.1f", x(0), y(0)))
+	for _, point := range report.Trajectory {
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x(float64(point.Pairs)), y(point.LLR)))
+	}
+
+	fmt.Fprintf(&svg, `<polyline fill="none" stroke="#2c3e50" stroke-width="1.5" points="%s"/>`, strings.Join(points, " "))
+	fmt.Fprintf(&svg, `<text x="%.1f" y="%.1f" font-size="10" text-anchor="end">%.0f pairs</text>`, width-pad, height-4, pairs)
+	svg.WriteString(`</svg>`)
+
+	// The chart is built from numbers only, so it is safe to embed.
+	return template.HTML(svg.String())
+}
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"signed":  func(x float64) string { return fmt.Sprintf("%+.1f", x) },
+	"fixed":   func(x float64) string { return fmt.Sprintf("%.1f", x) },
+	"llr":     func(x float64) string { return fmt.Sprintf("%.2f", x) },
+	"percent": func(x float64) string { return fmt.Sprintf("%.1f%%", 100*x) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Run.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.3em 0.8em; border-bottom: 1px solid #ddd; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.mono { font-family: monospace; }
+.muted { color: #888; }
+</style>
+</head>
+<body>
+<h1>{{.Run.Name}}</h1>
+<p class="muted">
+{{with .Run.Kind}}{{.}} · {{end}}{{with .Run.Game}}{{.}} · {{end}}
+{{if not .Run.Finished.IsZero}}finished {{.Run.Finished.Format "2006-01-02 15:04"}}{{end}}
+</p>
+
+{{with .Run.SPRT}}
+<h2>SPRT</h2>
+<table>
+<tr><td>Hypotheses</td><td>[{{fixed .Elo0}}, {{fixed .Elo1}}]</td></tr>
+<tr><td>LLR</td><td>{{llr .LLR}} ({{llr .Lower}}, {{llr .Upper}})</td></tr>
+<tr><td>Conclusion</td><td>{{if .Conclusion}}{{.Conclusion}} accepted{{else}}-{{end}}</td></tr>
+</table>
+
+<h3>Pentanomial</h3>
+<table>
+<tr><th>LL</th><th>LD</th><th>DD</th><th>WD</th><th>WW</th></tr>
+<tr>{{range $.Penta}}<td>{{.}}</td>{{end}}</tr>
+</table>
+
+<h3>LLR</h3>
+{{$.Chart}}
+{{end}}
+
+{{with .Run.Match}}
+<h2>Match</h2>
+<table>
+<tr><td>Pentanomial Elo</td><td>{{signed .PentaElo}} ± {{fixed .PentaError}}</td></tr>
+<tr><td>Normalized Elo</td><td>{{signed .NElo}} ± {{fixed .NEloError}}</td></tr>
+<tr><td>LOS</td><td>{{percent .LOS}}</td></tr>
+<tr><td>Draws</td><td>{{percent .Draws}}</td></tr>
+</table>
+
+<h3>Pentanomial</h3>
+<table>
+<tr><th>LL</th><th>LD</th><th>DD</th><th>WD</th><th>WW</th></tr>
+<tr>{{range $.Penta}}<td>{{.}}</td>{{end}}</tr>
+</table>
+{{end}}
+
+<h2>Ratings</h2>
+<table>
+<tr><th>Engine</th><th>Version</th><th>Elo</th><th>Error</th><th>Wins</th><th>Draws</th><th>Losses</th><th>Games</th></tr>
+{{range .Engines}}
+<tr><td>{{.Name}}</td><td>{{.ID}}</td><td>{{signed .Elo}}</td><td>{{fixed .Error}}</td><td>{{.Wins}}</td><td>{{.Draws}}</td><td>{{.Losses}}</td><td>{{.Games}}</td></tr>
+{{end}}
+</table>
+
+<h2>Crosstable</h2>
+<table>
+<tr><th></th>{{range .Engines}}<th>{{.Name}}</th>{{end}}</tr>
+{{range $i, $row := .Crosstable}}
+<tr><td>{{(index $.Engines $i).Name}}</td>{{range $j, $score := $row}}<td>{{if eq $i $j}}<span class="muted">-</span>{{else if $score.Games}}+{{$score.Wins}} ={{$score.Draws}} -{{$score.Losses}}{{end}}</td>{{end}}</tr>
+{{end}}
+</table>
+
+<h2>Terminations</h2>
+<table>
+<tr><th>Reason</th><th>Games</th></tr>
+{{range .Reasons}}<tr><td>{{.Reason}}</td><td>{{.Games}}</td></tr>{{end}}
+</table>
+
+<h2>Openings</h2>
+<table>
+<tr><th>Opening</th><th>White</th><th>Draw</th><th>Black</th><th></th></tr>
+{{range .Openings}}
+<tr><td class="mono">{{.Opening.FEN}}</td><td>{{.WhiteWins}}</td><td>{{.Draws}}</td><td>{{.BlackWins}}</td><td class="muted">{{.Verdict}}</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,205 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report builds reports of finished runs from the games stored in
+// the results database.
+package report
+
+import (
+	"math"
+	"sort"
+
+	"laptudirm.com/x/arbiter/pkg/eve/match"
+	"laptudirm.com/x/arbiter/pkg/eve/stats"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
+)
+
+// Report is a report of a run.
+type Report struct {
+	Run store.RunRecord
+
+	// Engines stores the results of each engine, and Crosstable[i][j] the
+	// results of engine i against engine j.
+	Engines    []Standing
+	Crosstable [][]Score
+
+	// Penta stores the number of game pairs with each result for the first
+	// engine, from loss-loss to win-win, if the run is an SPRT.
+	Penta [5]int
+
+	// Trajectory stores the LLR of an SPRT after each game pair.
+	Trajectory []Point
+
+	// Openings stores the results of each opening, and Reasons the number
+	// of games which ended for each reason, most common first.
+	Openings []*match.OpeningStats
+	Reasons  []Reason
+}
+
+// Standing is the score of an engine in a run.
+type Standing struct {
+	Name, ID string
+	Score
+
+	Elo, Error float64
+}
+
+// Score is a number of wins, draws, and losses.
+type Score struct {
+	Wins, Draws, Losses int
+}
+
+// Games returns the number of games in the score.
+func (score Score) Games() int {
+	return score.Wins + score.Draws + score.Losses
+}
+
+// add adds a game with the given result to the score.
+func (score *Score) add(result match.Result) {
+	switch result {
+	case match.Win:
+		score.Wins++
+	case match.Draw:
+		score.Draws++
+	case match.Loss:
+		score.Losses++
+	}
+}
+
+// Point is the state of an SPRT after a number of game pairs.
+type Point struct {
+	Pairs int
+	LLR   float64
+}
+
+// Reason is a reason for which games ended.
+type Reason struct {
+	Reason string
+	Games  int
+}
+
+// Build builds a report of the given run from its games.
+func Build(run store.RunRecord, games []store.Game) Report {
+	report := Report{Run: run}
+
+	// The engines are identified by their indices in the run, and named by
+	// the run's record or else by their games.
+	engines := len(run.Engines)
+	for _, game := range games {
+		for _, player := range game.Players {
+			if player >= engines {
+				engines = player + 1
+			}
+		}
+	}
+
+	report.Engines = make([]Standing, engines)
+	report.Crosstable = make([][]Score, engines)
+	for i := range report.Crosstable {
+		report.Crosstable[i] = make([]Score, engines)
+	}
+
+	for i, engine := range run.Engines {
+		report.Engines[i].Name, report.Engines[i].ID = engine.Name, engine.ID
+	}
+
+	var openings match.OpeningResults
+	reasons := map[string]int{}
+
+	for _, game := range games {
+		p1, p2 := game.Players[0], game.Players[1]
+		for i, player := range game.Players {
+			if report.Engines[player].Name == "" {
+				report.Engines[player].Name = game.Engines[i].Name
+			}
+
+			if report.Engines[player].ID == "" {
+				report.Engines[player].ID = game.Engines[i].ID
+			}
+		}
+
+		report.Engines[p1].add(game.Result)
+		report.Engines[p2].add(-game.Result)
+		report.Crosstable[p1][p2].add(game.Result)
+		report.Crosstable[p2][p1].add(-game.Result)
+
+		openings.Add(match.Opening{Position: match.Position{FEN: game.Opening}}, game.Result, game.White)
+		reasons[game.Reason]++
+	}
+
+	for i := range report.Engines {
+		standing := &report.Engines[i]
+		lower, elo, upper := stats.Elo(standing.Wins, standing.Draws, standing.Losses)
+		standing.Elo, standing.Error = elo, math.Abs(math.Max(upper-elo, elo-lower))
+	}
+
+	report.Openings = openings.Stats()
+
+	for reason, count := range reasons {
+		report.Reasons = append(report.Reasons, Reason{reason, count})
+	}
+
+	sort.Slice(report.Reasons, func(i, j int) bool {
+		if report.Reasons[i].Games != report.Reasons[j].Games {
+			return report.Reasons[i].Games > report.Reasons[j].Games
+		}
+
+		return report.Reasons[i].Reason < report.Reasons[j].Reason
+	})
+
+	if run.SPRT != nil {
+		report.Penta, report.Trajectory = trajectory(*run.SPRT, games)
+	}
+
+	if run.Match != nil {
+		report.Penta = run.Match.Penta
+	}
+
+	return report
+}
+
+// trajectory returns the pentanomial results of the given games of an SPRT
+// and its LLR after each game pair, in the order the pairs were completed.
+func trajectory(test store.SPRTRecord, games []store.Game) ([5]int, []Point) {
+	var penta [5]int
+	var score Score
+	var points []Point
+
+	pairs := map[int][]match.Result{}
+	for _, game := range games {
+		// Results are from the point of view of the test's first engine.
+		result := game.Result
+		if game.Players[0] == 1 {
+			result = -result
+		}
+
+		pairs[game.Pair] = append(pairs[game.Pair], result)
+		if len(pairs[game.Pair]) != 2 {
+			continue
+		}
+
+		pair := pairs[game.Pair]
+		score.add(pair[0])
+		score.add(pair[1])
+		penta[match.GetPairResult(pair[0], pair[1])+2]++
+
+		llr := stats.PentaSPRT(penta[0], penta[1], penta[2], penta[3], penta[4], test.Elo0, test.Elo1)
+		if test.Legacy {
+			llr = stats.SPRT(score.Wins, score.Draws, score.Losses, test.Elo0, test.Elo1)
+		}
+
+		points = append(points, Point{Pairs: len(points) + 1, LLR: llr})
+	}
+
+	return penta, points
+}
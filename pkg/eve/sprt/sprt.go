@@ -18,26 +18,67 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 	arbiter "laptudirm.com/x/arbiter/pkg/common"
+	"laptudirm.com/x/arbiter/pkg/eve/dashboard"
+	"laptudirm.com/x/arbiter/pkg/eve/events"
 	"laptudirm.com/x/arbiter/pkg/eve/match"
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
 	"laptudirm.com/x/arbiter/pkg/eve/stats"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
 )
 
 func NewTournament(config Config) (*SPRT, error) {
 	var sprt SPRT
 	sprt.Config = config
 
-	var err error
-	sprt.openings, err = match.NewBook(config.Openings)
+	// Tests without a name are named by their starting time, which is used
+	// to restart them and identifies their games in the results database.
+	if sprt.Name == "" {
+		sprt.Name = time.Now().Format(sprt.kind() + "-20060102-150405")
+	}
+
+	// Games need to be adjudicated either by a built-in oracle or by an
+	// engine acting as a referee.
+	if games.GetOracle(config.Game) == nil && !match.HasReferee(config.Engines[:]...) {
+		return nil, fmt.Errorf("new sprt: no oracle or referee for game %s", config.Game)
+	}
+
+	// Pentanomial statistics need both the games of a pair to be played
+	// with the same opening.
+	if repeat := config.Openings.Repeat; repeat != 0 && repeat != 2 {
+		return nil, fmt.Errorf("new sprt: openings must be repeated for 2 games, not %d", repeat)
+	}
+
+	// The engines are given time odds when their games are created, so that
+	// the config, which is stored when the test is paused, is unchanged.
+	engines, err := match.TimeOdds(config.Engines[:], config.TimeOdds)
 	if err != nil {
 		return nil, err
 	}
 
+	copy(sprt.engines[:], engines)
+	sprt.openingResults.MinGames = config.OpeningsMinGames
+
+	sprt.openings, err = match.NewBook(config.Game, config.Openings)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pairs of a restarted test are numbered from where it was stopped, so
+	// that its games can be told apart in the results database.
+	sprt.pairs.Store(int64((sprt.State.Wins + sprt.State.Losses + sprt.State.Draws) / 2))
+	sprt.issued.Store(int64(sprt.completed()))
+
 	sprt.results = make(chan PairResult)
 	sprt.complete = make(chan bool)
+	sprt.done = make(chan struct{})
 
 	return &sprt, nil
 }
@@ -47,62 +88,104 @@ type SPRT struct {
 
 	openings *match.OpeningBook
 
+	// engines are the engines' configs, with time odds applied.
+	engines [2]match.EngineConfig
+
+	// Results of the games played with each opening.
+	openingResults match.OpeningResults
+
 	results  chan PairResult
 	complete chan bool
 
-	number int
-	ended  bool
+	pairs atomic.Int64
+
+	// issued is the number of pairs the test had completed when it was
+	// started, and the number of pairs started since then.
+	issued atomic.Int64
+
+	// done is closed when the test ends, which stops its threads.
+	done chan struct{}
+
+	// Events is the stream the test's events are emitted to, if any.
+	Events *events.Stream
+
+	// Store is the database the test's games are stored in, if any.
+	Store *store.Store
+
+	// Dashboard is the live dashboard the test is shown on, if any.
+	Dashboard *dashboard.Dashboard
+
+	// Slots limits the number of game pairs played at once by tests which
+	// share their workers, if it is set.
+	Slots Slots
+
+	// Conclusion is the hypothesis accepted by the test once it has ended,
+	// which is empty for fixed-games matches.
+	Conclusion string
+
+	// started is the time at which the test was started, and ids stores the
+	// names the engines reported for themselves.
+	started time.Time
+	ids     [2]string
+
+	// resumed is the number of games which had been played when the test
+	// was started, which aren't counted when measuring its speed.
+	resumed int
+
+	// trajectory stores a snapshot of the test at every report. It isn't
+	// stored with the test's state, and is rebuilt from the stored games of
+	// restarted tests. trajectoryWritten is set once it is written to the
+	// TrajectoryOut file.
+	trajectory        []Snapshot
+	trajectoryWritten bool
 
 	a, b float64
 }
 
 func (sprt *SPRT) Start() error {
 	sprt.a, sprt.b = stats.StoppingBounds(sprt.Config.Alpha, sprt.Config.Beta)
+	sprt.started = time.Now()
+	sprt.resumed = sprt.State.Wins + sprt.State.Losses + sprt.State.Draws
 
 	go sprt.ResultHandler()
 	for i := 0; i < sprt.Config.Concurrency; i++ {
-		go sprt.Thread()
+		go sprt.Thread(i)
 	}
 
 	<-sprt.complete
 	return nil
 }
 
-func (sprt *SPRT) Thread() {
-	for !sprt.ended {
-		sprt.openings.Next()
-		opening := sprt.openings.Current()
+func (sprt *SPRT) Thread(worker int) {
+	// Engines are reused between the games played by a thread.
+	pool := match.NewPool(2)
+	defer pool.Close()
 
-		var pair PairResult
+	for {
+		// A slot is held while playing each pair, so that it can be given
+		// to another test between the pairs.
+		if sprt.Slots != nil {
+			sprt.Slots.Acquire()
+		}
 
-		p1, p2 := 0, 1
-		for game := 0; game < 2; game++ {
-			sprt.number++
-
-			match := Match{
-				Config: match.Config{
-					Game:        sprt.Config.Game,
-					PositionFEN: opening,
-					Engines: [2]match.EngineConfig{
-						sprt.Config.Engines[p1],
-						sprt.Config.Engines[p2],
-					},
-				},
+		number, opening, ok := sprt.NextPair()
+		if !ok {
+			sprt.release()
+			return
+		}
 
-				Number: sprt.number,
+		var pair PairResult
 
-				Player1: p1,
-				Player2: p2,
-			}
+		matches := sprt.PairMatches(number, opening)
+		for game := range matches {
+			matches[game].Worker = worker
 
-			result, err := sprt.RunGame(&match)
+			result, err := sprt.RunGame(&matches[game], pool)
 			if err != nil {
 				logrus.Error(err)
 			}
 
 			pair.Matches[game] = result
-
-			p1, p2 = p2, p1
 		}
 
 		pair.Result = match.GetPairResult(
@@ -110,7 +193,101 @@ func (sprt *SPRT) Thread() {
 			pair.Matches[1].Result,
 		)
 
-		sprt.results <- pair
+		sprt.release()
+
+		if !sprt.Submit(pair) {
+			return
+		}
+	}
+}
+
+// NextPair returns the number and the opening of the next game pair to be
+// played. It reports false if the test has ended, or if it is a fixed-games
+// match all of whose pairs have been started.
+func (sprt *SPRT) NextPair() (int, match.Opening, bool) {
+	select {
+	case <-sprt.done:
+		return 0, match.Opening{}, false
+	default:
+	}
+
+	// Fixed-games matches stop once all their pairs have been started.
+	// They are counted separately from the pair numbers, which skip the
+	// pairs left incomplete when the match was stopped.
+	if sprt.Config.Pairs > 0 && int(sprt.issued.Add(1)) > sprt.Config.Pairs {
+		return 0, match.Opening{}, false
+	}
+
+	opening := sprt.openings.Take()
+	number := int(sprt.pairs.Add(1))
+	return number, opening, true
+}
+
+// PairMatches returns the games of the pair with the given number, which
+// are played with the given opening and the engines' colors reversed.
+func (sprt *SPRT) PairMatches(number int, opening match.Opening) [2]Match {
+	var matches [2]Match
+
+	p1, p2 := 0, 1
+	for game := range matches {
+		// Games are numbered by their pair, so that the pairs can be played
+		// in any order.
+		n := 2*(number-1) + game + 1
+
+		matches[game] = Match{
+			Config: match.Config{
+				Game:         sprt.Config.Game,
+				PositionFEN:  opening.FEN,
+				OpeningMoves: opening.Moves,
+				Name:         fmt.Sprintf("game-%d", n),
+				Engines: [2]match.EngineConfig{
+					sprt.engines[p1],
+					sprt.engines[p2],
+				},
+			},
+
+			Number:  n,
+			Pair:    number,
+			Opening: opening,
+
+			Player1: p1,
+			Player2: p2,
+		}
+
+		p1, p2 = p2, p1
+	}
+
+	return matches
+}
+
+// Submit adds the result of a game pair to the test, and reports false if
+// the test has already ended.
+func (sprt *SPRT) Submit(pair PairResult) bool {
+	select {
+	case sprt.results <- pair:
+		return true
+	case <-sprt.done:
+		return false
+	}
+}
+
+// Done returns a channel which is closed when the test ends.
+func (sprt *SPRT) Done() <-chan struct{} {
+	return sprt.done
+}
+
+// Slots is a limited number of slots for playing game pairs, which may be
+// shared between tests.
+type Slots interface {
+	// Acquire waits for a free slot and takes it, and Release frees it.
+	Acquire()
+	Release()
+}
+
+// release releases the thread's slot, if the test's threads hold any.
+func (sprt *SPRT) release() {
+	if sprt.Slots != nil {
+		sprt.Slots.Release()
 	}
 }
 
@@ -119,27 +296,114 @@ type Match struct {
 	Number int
 
 	Player1, Player2 int
+
+	// Pair is the number of the game pair the game belongs to.
+	Pair int
+
+	// Worker is the index of the thread the game is played by.
+	Worker int
+
+	// Opening is the opening the game is played with.
+	Opening match.Opening
+}
+
+func (sprt *SPRT) RunGame(game *Match, pool *match.Pool) (Result, error) {
+	sprt.StartGame(game)
+
+	if sprt.Dashboard != nil || (sprt.Events != nil && sprt.Events.Moves) {
+		game.OnMove = func(move match.MoveInfo) {
+			sprt.Dashboard.Move(game.Worker, move)
+			sprt.Events.Emit(events.Move, events.MoveData{
+				Game:   game.Number,
+				Ply:    move.Ply,
+				Engine: game.Engines[move.Engine].Name,
+				Move:   move.Move,
+				TimeMs: move.Elapsed.Milliseconds(),
+			})
+		}
+	}
+
+	started := time.Now()
+	outcome := match.Run(&game.Config, pool)
+	return sprt.EndGame(game, outcome, started, time.Now()), nil
 }
 
-func (sprt *SPRT) RunGame(game *Match) (Result, error) {
+// StartGame reports the start of the given game.
+func (sprt *SPRT) StartGame(game *Match) {
 	logrus.Infof(
 		"\x1b[33mStarting\x1b[0m Game #%d: %s vs %s (\x1b[33m%s\x1b[0m)\n",
 		game.Number,
 		game.Engines[0].Name,
 		game.Engines[1].Name,
-		sprt.openings.Current(),
+		match.Position{FEN: game.PositionFEN, Moves: game.OpeningMoves},
 	)
 
-	score, reason := match.Run(&game.Config)
+	sprt.Events.Emit(events.GameStart, gameID(game))
+	sprt.Dashboard.StartGame(game.Worker, fmt.Sprintf("Game #%d", game.Number), [2]string{
+		game.Engines[0].Name, game.Engines[1].Name,
+	})
+}
+
+// EndGame records the given outcome of a game which was played between the
+// given times, and returns the game's result from the point of view of the
+// test's first engine.
+func (sprt *SPRT) EndGame(game *Match, outcome match.Outcome, started, finished time.Time) Result {
+	if sprt.Store != nil {
+		record := store.NewGame(&game.Config, outcome)
+		record.Run, record.Kind = sprt.Config.Name, sprt.kind()
+		record.Number, record.Pair = game.Number, game.Pair
+		record.Players = [2]int{game.Player1, game.Player2}
+		record.Started, record.Finished = started, finished
+
+		if err := sprt.Store.Add(record); err != nil {
+			logrus.Errorf("store game: %v", err)
+		}
+	}
+
+	end := events.GameEndData{
+		Game:   gameID(game),
+		White:  game.Engines[outcome.White].Name,
+		Result: outcome.Result.String(),
+		Reason: outcome.Reason,
+	}
+
+	switch outcome.Result {
+	case match.Win:
+		end.Winner = game.Engines[0].Name
+	case match.Loss:
+		end.Winner = game.Engines[1].Name
+	}
+
+	sprt.Events.Emit(events.GameEnd, end)
+
+	score, white := outcome.Result, outcome.White
 	if game.Player2 == 0 {
-		score = -score
+		score, white = -score, 1^white
 	}
 
-	return Result{
+	result := Result{
 		Match:  game,
 		Result: score,
-		Reason: reason,
-	}, nil
+		Reason: outcome.Reason,
+		White:  white,
+		Time:   outcome.Time,
+
+		Engines: outcome.Engines,
+	}
+
+	sprt.Dashboard.EndGame(game.Worker, fmt.Sprintf("Game #%d: %s", game.Number, result))
+	return result
+}
+
+// gameID returns the identification of the given game in events.
+func gameID(game *Match) events.Game {
+	return events.Game{
+		Number:  game.Number,
+		Engines: [2]string{game.Engines[0].Name, game.Engines[1].Name},
+		Opening: game.Opening.String(),
+
+		TimeControls: [2]string{game.Engines[0].TimeC, game.Engines[1].TimeC},
+	}
 }
 
 func (sprt *SPRT) ResultHandler() {
@@ -161,6 +425,12 @@ func (sprt *SPRT) ResultHandler() {
 		result_count++
 
 		for _, result := range pair.Matches {
+			sprt.State.Time[result.Match.Player1].Add(result.Time[0])
+			sprt.State.Time[result.Match.Player2].Add(result.Time[1])
+			sprt.openingResults.Add(result.Match.Opening, result.Result, result.White)
+			sprt.ids[result.Match.Player1] = result.Engines[0]
+			sprt.ids[result.Match.Player2] = result.Engines[1]
+
 			switch result.Result {
 			case match.Win:
 				sprt.State.Wins++
@@ -181,20 +451,66 @@ func (sprt *SPRT) ResultHandler() {
 
 		if result_count%5 == 0 {
 			sprt.Report()
+			sprt.EmitStats()
 		}
 
-		if llr := sprt.LLR(); llr <= sprt.a {
+		llr := sprt.LLR()
+		if sprt.Dashboard != nil {
+			_, eta := sprt.Estimate()
+			sprt.Dashboard.SetTable(sprt.table())
+			if sprt.Config.Pairs > 0 {
+				sprt.Dashboard.SetProgress(
+					float64(sprt.completed())/float64(sprt.Config.Pairs),
+					fmt.Sprintf("%d/%d pairs", sprt.completed(), sprt.Config.Pairs),
+					eta,
+				)
+			} else {
+				sprt.Dashboard.SetProgress(
+					(llr-sprt.a)/(sprt.b-sprt.a),
+					fmt.Sprintf("LLR %.2f (%.2f, %.2f)", llr, sprt.a, sprt.b),
+					eta,
+				)
+			}
+		}
+
+		// Fixed-games matches don't have a stopping rule, and end without
+		// accepting either hypothesis.
+		var conclusion string
+		switch {
+		case sprt.Config.Pairs > 0:
+			if sprt.completed() < sprt.Config.Pairs {
+				continue
+			}
+
+			sprt.Dashboard.Stop()
+			fmt.Println("\n\x1b[34mMatch Finished")
+		case llr <= sprt.a:
+			sprt.Dashboard.Stop()
 			fmt.Println("\n\x1b[31mH0 Accepted")
-		} else if llr >= sprt.b {
+			conclusion = "H0"
+		case llr >= sprt.b:
+			sprt.Dashboard.Stop()
 			fmt.Println("\n\x1b[32mH1 Accepted")
-		} else {
+			conclusion = "H1"
+		default:
 			continue
 		}
 
 		sprt.Report()
+		sprt.ReportTime()
+		sprt.ReportOpenings()
+
+		sprt.EmitStats()
+		sprt.SaveRun(conclusion)
+		sprt.Events.Emit(events.RunEnd, events.RunEndData{
+			Kind:       sprt.kind(),
+			Games:      sprt.State.Wins + sprt.State.Losses + sprt.State.Draws,
+			Conclusion: conclusion,
+		})
 
 		fmt.Print("\x1b[0m")
-		close(sprt.results)
+		sprt.Conclusion = conclusion
+		close(sprt.done)
 		sprt.complete <- true
 		return
 	}
@@ -202,9 +518,22 @@ func (sprt *SPRT) ResultHandler() {
 }
 
 func (sprt *SPRT) Report() {
+	sprt.snapshot(sprt.current())
+
 	data, _ := yaml.Marshal(sprt.Wrap())
 	os.WriteFile(filepath.Join(arbiter.Directory, "paused", "sprt", sprt.Name), data, 0777)
 
+	for _, line := range sprt.table() {
+		fmt.Println(line)
+	}
+}
+
+// table returns the lines of the table of the test's results.
+func (sprt *SPRT) table() []string {
+	if sprt.Config.Pairs > 0 {
+		return sprt.matchTable()
+	}
+
 	lower, elo, upper := stats.Elo(sprt.State.Wins, sprt.State.Draws, sprt.State.Losses)
 	err := math.Abs(math.Max(upper-elo, elo-lower))
 
@@ -216,10 +545,13 @@ func (sprt *SPRT) Report() {
 	llr_str := fmt.Sprintf("║ LLR   | %.2f (%.2f, %.2f) [%.2f, %.2f]", llr, sprt.a, sprt.b, sprt.Config.Elo0, sprt.Config.Elo1)
 	gam_str := fmt.Sprintf("║ GAMES | N: %d W: %d L: %d D: %d", n, sprt.State.Wins, sprt.State.Losses, sprt.State.Draws)
 
-	fmt.Println("╔═════════════════════════════════════════════════╗")
-	fmt.Printf("%-50s║\n", elo_str)
-	fmt.Printf("%-50s║\n", llr_str)
-	fmt.Printf("%-50s║\n", gam_str)
+	lines := []string{
+		"╔═════════════════════════════════════════════════╗",
+		fmt.Sprintf("%-50s║", elo_str),
+		fmt.Sprintf("%-50s║", llr_str),
+		fmt.Sprintf("%-50s║", gam_str),
+	}
+
 	if !sprt.Config.Legacy {
 		penta_str := fmt.Sprintf(
 			"║ PENTA | [%d, %d, %d, %d, %d]",
@@ -227,11 +559,272 @@ func (sprt *SPRT) Report() {
 			sprt.State.DrawDraw,
 			sprt.State.WinDraw, sprt.State.WinWin,
 		)
-		fmt.Printf("%-50s║\n", penta_str)
+		lines = append(lines, fmt.Sprintf("%-50s║", penta_str))
+	}
+
+	if estimate, eta := sprt.Estimate(); eta > 0 {
+		eta_str := fmt.Sprintf(
+			"║ ETA   | %.0f games, %s (H1 %.1f%%)",
+			estimate.Games, eta.Round(time.Second), 100*estimate.H1,
+		)
+		lines = append(lines, fmt.Sprintf("%-50s║", eta_str))
+	}
+
+	// The sparkline is padded by hand since its characters are wider than
+	// a byte, unlike the rest of the report.
+	trend := "║ TREND | " + sparkline(sprt.trajectory, sprt.a, sprt.b, 38)
+	lines = append(lines, trend+strings.Repeat(" ", 48-utf8.RuneCountInString(trend))+"║")
+	return append(lines, "╚═════════════════════════════════════════════════╝")
+}
+
+// matchTable returns the lines of the table of a fixed-games match's results.
+func (sprt *SPRT) matchTable() []string {
+	w, l, d := sprt.State.Wins, sprt.State.Losses, sprt.State.Draws
+	n := w + l + d
+
+	elo_lower, elo, elo_upper := stats.Elo(w, d, l)
+	penta_lower, penta, penta_upper := stats.PentaElo(
+		sprt.State.LossLoss, sprt.State.DrawLoss,
+		sprt.State.DrawDraw,
+		sprt.State.WinDraw, sprt.State.WinWin,
+	)
+	nelo_lower, nelo, nelo_upper := stats.PentaNElo(
+		sprt.State.LossLoss, sprt.State.DrawLoss,
+		sprt.State.DrawDraw,
+		sprt.State.WinDraw, sprt.State.WinWin,
+	)
+
+	draws := 0.0
+	if n > 0 {
+		draws = 100 * float64(d) / float64(n)
+	}
+
+	lines := []string{
+		"╔═════════════════════════════════════════════════╗",
+		fmt.Sprintf("%-50s║", fmt.Sprintf("║ ELO   | %.2f +- %.2f (95%%)", elo, math.Abs(math.Max(elo_upper-elo, elo-elo_lower)))),
+		fmt.Sprintf("%-50s║", fmt.Sprintf("║ PELO  | %.2f +- %.2f (95%%)", penta, math.Abs(math.Max(penta_upper-penta, penta-penta_lower)))),
+		fmt.Sprintf("%-50s║", fmt.Sprintf("║ NELO  | %.2f +- %.2f (95%%)", nelo, math.Abs(math.Max(nelo_upper-nelo, nelo-nelo_lower)))),
+		fmt.Sprintf("%-50s║", fmt.Sprintf("║ LOS   | %.2f%%", 100*stats.LOS(w, l))),
+		fmt.Sprintf("%-50s║", fmt.Sprintf("║ DRAWS | %.2f%%", draws)),
+		fmt.Sprintf("%-50s║", fmt.Sprintf("║ GAMES | N: %d W: %d L: %d D: %d", n, w, l, d)),
+		fmt.Sprintf("%-50s║", fmt.Sprintf(
+			"║ PENTA | [%d, %d, %d, %d, %d]",
+			sprt.State.LossLoss, sprt.State.DrawLoss,
+			sprt.State.DrawDraw,
+			sprt.State.WinDraw, sprt.State.WinWin,
+		)),
+		fmt.Sprintf("%-50s║", fmt.Sprintf("║ PAIRS | %d/%d", sprt.completed(), sprt.Config.Pairs)),
+	}
+
+	if estimate, eta := sprt.Estimate(); eta > 0 {
+		eta_str := fmt.Sprintf("║ ETA   | %.0f games, %s", estimate.Games, eta.Round(time.Second))
+		lines = append(lines, fmt.Sprintf("%-50s║", eta_str))
+	}
+
+	return append(lines, "╚═════════════════════════════════════════════════╝")
+}
+
+// completed returns the number of game pairs the test has completed.
+func (sprt *SPRT) completed() int {
+	return sprt.State.WinWin + sprt.State.WinDraw + sprt.State.DrawDraw + sprt.State.DrawLoss + sprt.State.LossLoss
+}
+
+// kind returns the kind of the test, which is a match if it is played for a
+// fixed number of games.
+func (sprt *SPRT) kind() string {
+	if sprt.Config.Pairs > 0 {
+		return "match"
+	}
+
+	return "sprt"
+}
+
+// Estimate estimates the rest of the test from its results so far, and the
+// time it will take to conclude at the speed it has been played at, which is
+// zero if it is not known yet.
+func (sprt *SPRT) Estimate() (stats.Estimate, time.Duration) {
+	var estimate stats.Estimate
+	switch {
+	case sprt.Config.Pairs > 0:
+		// Fixed-games matches always play all their games.
+		estimate.Games = float64(2 * (sprt.Config.Pairs - sprt.completed()))
+	case sprt.Config.Legacy:
+		estimate = stats.SPRTEstimate(
+			sprt.State.Wins, sprt.State.Draws, sprt.State.Losses,
+			sprt.Config.Elo0, sprt.Config.Elo1,
+			sprt.LLR(), sprt.a, sprt.b,
+		)
+	default:
+		estimate = stats.PentaEstimate(
+			sprt.State.LossLoss, sprt.State.DrawLoss,
+			sprt.State.DrawDraw,
+			sprt.State.WinDraw, sprt.State.WinWin,
+			sprt.Config.Elo0, sprt.Config.Elo1,
+			sprt.LLR(), sprt.a, sprt.b,
+		)
+	}
+
+	played := sprt.State.Wins + sprt.State.Losses + sprt.State.Draws - sprt.resumed
+	if played <= 0 {
+		return estimate, 0
+	}
+
+	perGame := time.Since(sprt.started) / time.Duration(played)
+	return estimate, time.Duration(estimate.Games * float64(perGame))
+}
+
+// EmitStats emits the current statistics of the test to its event stream.
+func (sprt *SPRT) EmitStats() {
+	if sprt.Events == nil {
+		return
+	}
+
+	lower, elo, upper := stats.Elo(sprt.State.Wins, sprt.State.Draws, sprt.State.Losses)
+	sprt.Events.Emit(events.Stats, events.SPRTStats{
+		Games:  sprt.State.Wins + sprt.State.Losses + sprt.State.Draws,
+		Wins:   sprt.State.Wins,
+		Losses: sprt.State.Losses,
+		Draws:  sprt.State.Draws,
+		Penta: [5]int{
+			sprt.State.LossLoss, sprt.State.DrawLoss,
+			sprt.State.DrawDraw,
+			sprt.State.WinDraw, sprt.State.WinWin,
+		},
+
+		Elo:      elo,
+		EloError: math.Abs(math.Max(upper-elo, elo-lower)),
+
+		LLR:   sprt.LLR(),
+		Lower: sprt.a,
+		Upper: sprt.b,
+	})
+}
+
+// SaveRun stores the record of the test, which has finished with the given
+// hypothesis accepted, in its results database. Fixed-games matches have no
+// hypotheses, and their record has their pentanomial statistics instead.
+func (sprt *SPRT) SaveRun(conclusion string) {
+	if sprt.Store == nil {
+		return
+	}
+
+	lower, elo, upper := stats.Elo(sprt.State.Wins, sprt.State.Draws, sprt.State.Losses)
+	run := store.RunRecord{
+		Name:     sprt.Name,
+		Kind:     sprt.kind(),
+		Game:     sprt.Config.Game,
+		Started:  sprt.started,
+		Finished: time.Now(),
+		Games:    sprt.State.Wins + sprt.State.Losses + sprt.State.Draws,
+		TimeOdds: sprt.Config.TimeOdds,
+		Engines: []store.EngineRecord{
+			{
+				Name:   sprt.Config.Engines[0].Name,
+				ID:     sprt.ids[0],
+				TimeC:  sprt.engines[0].TimeC,
+				Wins:   sprt.State.Wins,
+				Losses: sprt.State.Losses,
+				Draws:  sprt.State.Draws,
+				Elo:    elo,
+				Error:  math.Abs(math.Max(upper-elo, elo-lower)),
+			},
+			{
+				Name:   sprt.Config.Engines[1].Name,
+				ID:     sprt.ids[1],
+				TimeC:  sprt.engines[1].TimeC,
+				Wins:   sprt.State.Losses,
+				Losses: sprt.State.Wins,
+				Draws:  sprt.State.Draws,
+				Elo:    -elo,
+				Error:  math.Abs(math.Max(upper-elo, elo-lower)),
+			},
+		},
+	}
+
+	penta := [5]int{
+		sprt.State.LossLoss, sprt.State.DrawLoss,
+		sprt.State.DrawDraw,
+		sprt.State.WinDraw, sprt.State.WinWin,
+	}
+
+	// Fixed-games matches have no stopping rule, so the statistics used to
+	// compare the engines are stored instead of the test's.
+	if sprt.Config.Pairs > 0 {
+		penta_lower, penta_elo, penta_upper := stats.PentaElo(penta[0], penta[1], penta[2], penta[3], penta[4])
+		nelo_lower, nelo, nelo_upper := stats.PentaNElo(penta[0], penta[1], penta[2], penta[3], penta[4])
+
+		draws := 0.0
+		if run.Games > 0 {
+			draws = float64(sprt.State.Draws) / float64(run.Games)
+		}
+
+		run.Match = &store.MatchRecord{
+			Pairs: sprt.Config.Pairs,
+			Penta: penta,
+
+			PentaElo:   penta_elo,
+			PentaError: math.Abs(math.Max(penta_upper-penta_elo, penta_elo-penta_lower)),
+			NElo:       nelo,
+			NEloError:  math.Abs(math.Max(nelo_upper-nelo, nelo-nelo_lower)),
+
+			LOS:   stats.LOS(sprt.State.Wins, sprt.State.Losses),
+			Draws: draws,
+		}
+	} else {
+		run.SPRT = &store.SPRTRecord{
+			Elo0:  sprt.Config.Elo0,
+			Elo1:  sprt.Config.Elo1,
+			Alpha: sprt.Config.Alpha,
+			Beta:  sprt.Config.Beta,
+
+			LLR:   sprt.LLR(),
+			Lower: sprt.a,
+			Upper: sprt.b,
+
+			Penta: penta,
+
+			Legacy:     sprt.Config.Legacy,
+			Conclusion: conclusion,
+		}
+	}
+
+	if err := sprt.Store.SaveRun(run); err != nil {
+		logrus.Errorf("store run: %v", err)
+	}
+}
+
+// ReportTime prints the time usage statistics of the engines.
+func (sprt *SPRT) ReportTime() {
+	fmt.Println("╔═════════════════════════════════════════════════╗")
+	for i, engine := range sprt.Config.Engines {
+		usage := sprt.State.Time[i]
+		fmt.Printf("%-50s║\n", fmt.Sprintf("║ TIME  | %s", engine.Name))
+		fmt.Printf("%-50s║\n", fmt.Sprintf(
+			"║       | avg %s max %s",
+			usage.Average().Round(time.Millisecond),
+			usage.Max.Round(time.Millisecond),
+		))
+		fmt.Printf("%-50s║\n", fmt.Sprintf(
+			"║       | overruns %d forfeits %d",
+			usage.Overruns, usage.Forfeits,
+		))
 	}
 	fmt.Println("╚═════════════════════════════════════════════════╝")
 }
 
+// ReportOpenings prints the results of the games played with each opening,
+// and stores them in the OpeningsOut file if it is set.
+func (sprt *SPRT) ReportOpenings() {
+	sprt.openingResults.Report()
+	if sprt.Config.OpeningsOut == "" {
+		return
+	}
+
+	if err := sprt.openingResults.WriteCSV(sprt.Config.OpeningsOut); err != nil {
+		logrus.Error(err)
+	}
+}
+
 func (sprt *SPRT) LLR() float64 {
 	if sprt.Config.Legacy {
 		return stats.SPRT(
@@ -254,9 +847,105 @@ func (sprt *SPRT) LLR() float64 {
 	)
 }
 
+// Restore rebuilds the state of the test from its games stored in the
+// results database, instead of the counters stored when it was stopped.
+// Pairs which weren't completed aren't counted, like when they are played.
+// The stored counters are kept if the database has fewer pairs than them,
+// like when some of the test's games weren't stored.
+func (sprt *SPRT) Restore(games []store.Game) {
+	previous := sprt.State
+	sprt.State.Wins, sprt.State.Losses, sprt.State.Draws = 0, 0, 0
+	sprt.State.WinWin, sprt.State.WinDraw, sprt.State.DrawDraw = 0, 0, 0
+	sprt.State.DrawLoss, sprt.State.LossLoss = 0, 0
+	sprt.State.Time = [2]match.TimeUsage{}
+	sprt.trajectory = nil
+
+	pairs := map[int][]store.Game{}
+	order := []int{}
+	for _, game := range games {
+		if _, found := pairs[game.Pair]; !found {
+			order = append(order, game.Pair)
+		}
+
+		pairs[game.Pair] = append(pairs[game.Pair], game)
+	}
+
+	for _, number := range order {
+		pair := pairs[number]
+		if len(pair) != 2 {
+			continue
+		}
+
+		var results [2]match.Result
+		for i, game := range pair {
+			// Results are stored from the point of view of the game's first
+			// engine, and the test's are from that of its first engine.
+			results[i] = game.Result
+			if game.Players[0] == 1 {
+				results[i] = -results[i]
+			}
+
+			switch results[i] {
+			case match.Win:
+				sprt.State.Wins++
+			case match.Loss:
+				sprt.State.Losses++
+			case match.Draw:
+				sprt.State.Draws++
+			}
+
+			sprt.State.Time[game.Players[0]].Add(game.Time[0])
+			sprt.State.Time[game.Players[1]].Add(game.Time[1])
+		}
+
+		switch match.GetPairResult(results[0], results[1]) {
+		case match.WinWin:
+			sprt.State.WinWin++
+		case match.WinDraw:
+			sprt.State.WinDraw++
+		case match.DrawDraw:
+			sprt.State.DrawDraw++
+		case match.DrawLoss:
+			sprt.State.DrawLoss++
+		case match.LossLoss:
+			sprt.State.LossLoss++
+		}
+
+		// The trajectory is rebuilt with a snapshot every 5 pairs, as often
+		// as the test is reported.
+		if sprt.completed()%5 == 0 {
+			sprt.trajectory = append(sprt.trajectory, sprt.current())
+		}
+	}
+
+	restored := sprt.State.WinWin + sprt.State.WinDraw + sprt.State.DrawDraw + sprt.State.DrawLoss + sprt.State.LossLoss
+	stored := previous.WinWin + previous.WinDraw + previous.DrawDraw + previous.DrawLoss + previous.LossLoss
+	if restored < stored {
+		logrus.Warnf("restore sprt: only %d of %d pairs are stored, keeping the counters", restored, stored)
+		sprt.State = previous
+		sprt.trajectory = nil
+		return
+	}
+
+	// New pairs are numbered after all the stored ones, including the ones
+	// which weren't completed, so that their games aren't mixed up.
+	last := 0
+	for _, number := range order {
+		if number > last {
+			last = number
+		}
+	}
+
+	if last > int(sprt.pairs.Load()) {
+		sprt.pairs.Store(int64(last))
+	}
+
+	sprt.issued.Store(int64(sprt.completed()))
+}
+
 func (sprt *SPRT) Wrap() Config {
 	config := sprt.Config
-	sprt.Openings = sprt.openings.Wrap()
+	config.Openings = sprt.openings.Wrap()
 	return config
 }
 
@@ -270,6 +959,15 @@ type Result struct {
 
 	Result match.Result
 	Reason string
+
+	// White is the index of the engine which played White.
+	White int
+
+	// Time usage statistics of the engines in the order they played.
+	Time [2]match.TimeUsage
+
+	// Names the engines reported for themselves in the order they played.
+	Engines [2]string
 }
 
 func (result Result) String() string {
@@ -319,8 +1017,26 @@ type Config struct {
 	// PGNOut string // File to store the game PGNs at.
 	// EPDOut string // File to store the game ends EPD at.
 
+	// TimeOdds is the ratio of the time given to the first engine to that
+	// given to the second, which plays with its own time control.
+	TimeOdds float64 `yaml:"time-odds"`
+
+	// Pairs is the number of game pairs played by a fixed-games match,
+	// which is run without the test's stopping rule if it is set.
+	Pairs int `yaml:"pairs"`
+
+	OpeningsOut   string `yaml:"openings-out"`   // File to store the results of each opening at.
+	TrajectoryOut string `yaml:"trajectory-out"` // File to store the test's trajectory at, as CSV.
+
+	// OpeningsMinGames is the number of games an opening has to be played
+	// in before it is reported as lopsided, which is 6 if it is zero.
+	OpeningsMinGames int `yaml:"openings-min-games"`
+
 	State struct {
 		Wins, Losses, Draws                           int
 		WinWin, WinDraw, DrawDraw, DrawLoss, LossLoss int
+
+		// Time usage statistics of each engine.
+		Time [2]match.TimeUsage
 	}
 }
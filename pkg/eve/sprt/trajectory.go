@@ -0,0 +1,154 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sprt
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"laptudirm.com/x/arbiter/pkg/eve/stats"
+)
+
+// Snapshot is the state of a test after a number of games, from the point
+// of view of its first engine. Penta stores the number of pairs with each
+// result, from loss-loss to win-win.
+type Snapshot struct {
+	Games int
+
+	LLR        float64
+	Elo, Error float64
+
+	Penta [5]int
+}
+
+// snapshot adds the given snapshot to the test's trajectory, and to the
+// TrajectoryOut file if it is set.
+func (sprt *SPRT) snapshot(snapshot Snapshot) {
+	// The test is reported more than once after its last game.
+	if n := len(sprt.trajectory); n > 0 && sprt.trajectory[n-1].Games == snapshot.Games {
+		return
+	}
+
+	sprt.trajectory = append(sprt.trajectory, snapshot)
+
+	if sprt.Config.TrajectoryOut == "" {
+		return
+	}
+
+	// Tests which are started anew replace the file of any earlier test,
+	// and restarted tests add to it.
+	truncate := !sprt.trajectoryWritten && sprt.resumed == 0
+	sprt.trajectoryWritten = true
+
+	if err := appendTrajectory(sprt.Config.TrajectoryOut, snapshot, truncate); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// current returns a snapshot of the test as it is now.
+func (sprt *SPRT) current() Snapshot {
+	lower, elo, upper := stats.Elo(sprt.State.Wins, sprt.State.Draws, sprt.State.Losses)
+
+	return Snapshot{
+		Games: sprt.State.Wins + sprt.State.Losses + sprt.State.Draws,
+		LLR:   sprt.LLR(),
+		Elo:   elo,
+		Error: math.Abs(math.Max(upper-elo, elo-lower)),
+		Penta: [5]int{
+			sprt.State.LossLoss, sprt.State.DrawLoss,
+			sprt.State.DrawDraw,
+			sprt.State.WinDraw, sprt.State.WinWin,
+		},
+	}
+}
+
+// appendTrajectory adds the given snapshot to the CSV file at the given
+// path, which is emptied first if truncate is set. The header is written
+// to files which are empty.
+func appendTrajectory(path string, snapshot Snapshot, truncate bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	if info.Size() == 0 {
+		_ = writer.Write([]string{"games", "llr", "elo", "error", "ll", "ld", "dd", "wd", "ww"})
+	}
+
+	record := []string{
+		strconv.Itoa(snapshot.Games),
+		fmt.Sprintf("%.4f", snapshot.LLR),
+		fmt.Sprintf("%.2f", snapshot.Elo),
+		fmt.Sprintf("%.2f", snapshot.Error),
+	}
+
+	for _, pairs := range snapshot.Penta {
+		record = append(record, strconv.Itoa(pairs))
+	}
+
+	_ = writer.Write(record)
+	writer.Flush()
+	return writer.Error()
+}
+
+// sparkBars are the characters of a sparkline, from the lowest to the
+// highest.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline returns a sparkline of the LLRs of the given trajectory, scaled
+// between the given bounds, which is at most width characters long. Longer
+// trajectories are shown by the last snapshot of every segment.
+func sparkline(trajectory []Snapshot, lower, upper float64, width int) string {
+	if len(trajectory) == 0 || upper <= lower {
+		return ""
+	}
+
+	columns := len(trajectory)
+	if columns > width {
+		columns = width
+	}
+
+	line := make([]rune, columns)
+	for i := range line {
+		snapshot := trajectory[(i+1)*len(trajectory)/columns-1]
+
+		level := int((snapshot.LLR - lower) / (upper - lower) * float64(len(sparkBars)))
+		if level < 0 {
+			level = 0
+		} else if level >= len(sparkBars) {
+			level = len(sparkBars) - 1
+		}
+
+		line[i] = sparkBars[level]
+	}
+
+	return string(line)
+}
@@ -0,0 +1,152 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import "math"
+
+// Estimate is the expected course of an SPRT.
+type Estimate struct {
+	// H0 and H1 are the probabilities of the test accepting each hypothesis.
+	H0, H1 float64
+
+	// Games is the expected number of games left before the test concludes.
+	Games float64
+}
+
+// PentaEstimate estimates the course of a pentanomial SPRT from the results
+// of its game pairs and its current llr, assuming that the engines' true
+// strength difference and the variance of the pair results are the ones
+// measured so far.
+func PentaEstimate(lls, lds, dds, wds, wws int, elo0, elo1, llr, lower, upper float64) Estimate {
+	N := float64(lls+lds+dds+wds+wws) + 2.5 // total number of pairs
+
+	ll := (float64(lls) + 0.5) / N // measured loss-loss probability
+	ld := (float64(lds) + 0.5) / N // measured loss-draw probability
+	dd := (float64(dds) + 0.5) / N // measured win-loss/draw-draw probability
+	wd := (float64(wds) + 0.5) / N // measured win-draw probability
+	ww := (float64(wws) + 0.5) / N // measured win-win probability
+
+	// empirical mean of random variable
+	mu := ww + 0.75*wd + 0.5*dd + 0.25*ld
+
+	// variance of the random variable
+	variance := ww*math.Pow(1-mu, 2) +
+		wd*math.Pow(0.75-mu, 2) +
+		dd*math.Pow(0.50-mu, 2) +
+		ld*math.Pow(0.25-mu, 2) +
+		ll*math.Pow(0.00-mu, 2)
+
+	r := math.Sqrt(variance)
+	h1, pairs := estimate(mu, variance, nEloToScore(elo0, r), nEloToScore(elo1, r), llr, lower, upper)
+	return Estimate{H0: 1 - h1, H1: h1, Games: 2 * pairs}
+}
+
+// SPRTEstimate estimates the course of a trinomial SPRT from the results of
+// its games and its current llr, assuming that the engines' true strength
+// difference and draw rate are the ones measured so far.
+func SPRTEstimate(ws, ds, ls int, elo0, elo1, llr, lower, upper float64) Estimate {
+	w := float64(ws) + 0.5
+	d := float64(ds) + 0.5
+	l := float64(ls) + 0.5
+
+	N := w + d + l // total number of games
+	w, d, l = w/N, d/N, l/N
+
+	_, dlo := wdlToElo(w, d, l)
+	h1, games := estimate(w+d/2, variance(w, d, l), score(elo0, dlo), score(elo1, dlo), llr, lower, upper)
+	return Estimate{H0: 1 - h1, H1: h1, Games: games}
+}
+
+// PlanEstimate estimates the course of an SPRT with the given hypotheses and
+// error probabilities before it is started, assuming that the engines' true
+// elo difference, in the same units as the hypotheses, is elo, and that the
+// given ratio of the games are drawn.
+func PlanEstimate(elo, draws, elo0, elo1, alpha, beta float64, legacy bool) Estimate {
+	lower, upper := StoppingBounds(alpha, beta)
+
+	// draw elo of evenly matched engines with the given draw ratio
+	dlo := 400 * math.Log10((1+draws)/(1-draws))
+
+	if legacy {
+		w, d, l := eloToWDL(elo, dlo)
+		h1, games := estimate(w+d/2, variance(w, d, l), score(elo0, dlo), score(elo1, dlo), 0, lower, upper)
+		return Estimate{H0: 1 - h1, H1: h1, Games: games}
+	}
+
+	// The results of the games of a pair are assumed to be independent, so
+	// the variance of the pair's score is half of that of a game.
+	w, d, l := eloToWDL(0, dlo)
+	r := math.Sqrt(variance(w, d, l) / 2)
+
+	h1, pairs := estimate(
+		nEloToScore(elo, r), r*r,
+		nEloToScore(elo0, r), nEloToScore(elo1, r),
+		0, lower, upper,
+	)
+
+	return Estimate{H0: 1 - h1, H1: h1, Games: 2 * pairs}
+}
+
+// score returns the expected score of a game with the given elo and draw elo.
+func score(elo, dlo float64) float64 {
+	w, d, _ := eloToWDL(elo, dlo)
+	return w + d/2
+}
+
+// variance returns the variance of the score of a game with the given wdl
+// probabilities.
+func variance(w, d, l float64) float64 {
+	mu := w + d/2
+	return w*math.Pow(1-mu, 2) +
+		d*math.Pow(0.5-mu, 2) +
+		l*math.Pow(0-mu, 2)
+}
+
+// estimate returns the probability of an SPRT accepting H1 and the expected
+// number of trials left before it concludes, given its current llr and its
+// stopping bounds, if the scores of its trials have the given mean and
+// variance, and the hypotheses correspond to the mean scores mu0 and mu1.
+//
+// The llr is approximated by a brownian motion with the drift and variance
+// of the llr of a single trial under a normal model of the scores, for which
+// the probabilities of hitting each bound and the expected time taken to do
+// so are known.
+func estimate(mu, variance, mu0, mu1, llr, lower, upper float64) (h1, trials float64) {
+	if llr <= lower {
+		return 0, 0
+	} else if llr >= upper {
+		return 1, 0
+	}
+
+	// distances of the bounds from the current llr
+	a, b := lower-llr, upper-llr
+
+	drift := (mu1 - mu0) * (2*mu - mu0 - mu1) / (2 * variance)
+	spread := (mu1 - mu0) * (mu1 - mu0) / variance
+
+	gamma := 2 * drift / spread
+	switch {
+	case math.Abs(gamma*(b-a)) < 1e-9:
+		// The llr has no drift, so it is a simple random walk.
+		return -a / (b - a), -a * b / spread
+
+	case gamma > 0:
+		h1 = math.Expm1(gamma*a) / math.Expm1(gamma*(a-b))
+
+	default:
+		h1 = (math.Exp(gamma*b) - math.Exp(gamma*(b-a))) / -math.Expm1(gamma*(b-a))
+	}
+
+	return h1, math.Max((h1*b+(1-h1)*a)/drift, 0)
+}
@@ -84,3 +84,35 @@ func PentaElo(lls, lds, dds, wds, wws int) (muMin float64, mu float64, muMax flo
 
 	return clampElo(muMin), clampElo(mu), clampElo(muMax)
 }
+
+// PentaNElo calculates the best fit normalized elo for the given game pair
+// results, which is the elo scaled by the deviation of the pair results, so
+// that it doesn't depend on the draw ratio. It also calculates the maximum
+// and minimum values of that estimate (the error bounds) with p < 0.05.
+func PentaNElo(lls, lds, dds, wds, wws int) (neloMin float64, nelo float64, neloMax float64) {
+	N := float64(lls+lds+dds+wds+wws) + 2.5 // total number of pairs
+
+	ll := (float64(lls) + 0.5) / N // measured loss-loss probability
+	ld := (float64(lds) + 0.5) / N // measured loss-draw probability
+	dd := (float64(dds) + 0.5) / N // measured win-loss/draw-draw probability
+	wd := (float64(wds) + 0.5) / N // measured win-draw probability
+	ww := (float64(wws) + 0.5) / N // measured win-win probability
+
+	// empirical mean of random variable
+	mu := ww + 0.75*wd + 0.5*dd + 0.25*ld
+
+	// standard deviation (multiplied by sqrt of N) of the random variable
+	r := math.Sqrt(
+		ww*math.Pow(1-mu, 2) +
+			wd*math.Pow(0.75-mu, 2) +
+			dd*math.Pow(0.50-mu, 2) +
+			ld*math.Pow(0.25-mu, 2) +
+			ll*math.Pow(0.00-mu, 2),
+	)
+
+	sigma := r / math.Sqrt(N)
+
+	return scoreToNElo(mu+phiInv(0.975)*sigma, r),
+		scoreToNElo(mu, r),
+		scoreToNElo(mu+phiInv(0.025)*sigma, r)
+}
@@ -0,0 +1,228 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Simulation is a Monte Carlo simulation of an SPRT.
+type Simulation struct {
+	Elo0, Elo1  float64 // The null and the alternate elo hypotheses.
+	Alpha, Beta float64 // Confidence bounds for Error types I and II.
+
+	// Legacy makes the simulated test trinomial instead of pentanomial.
+	Legacy bool
+
+	// WDL is the probability of winning, drawing, and losing a game. The
+	// games are independent, unless Penta, the probability of each game
+	// pair result from loss-loss to win-win, is set, in which case the game
+	// pairs are drawn from it instead.
+	WDL   [3]float64
+	Penta []float64
+
+	// Runs is the number of tests simulated, which are stopped without a
+	// conclusion after MaxGames games, if it is set.
+	Runs     int
+	MaxGames int
+
+	// Seed is the seed of the random number generator.
+	Seed int64
+}
+
+// SimulationResult is the result of a simulation.
+type SimulationResult struct {
+	// H0 and H1 are the number of tests which accepted each hypothesis,
+	// and Unfinished the number which were stopped without a conclusion.
+	H0, H1, Unfinished int
+
+	// Games is the number of games played by each test, in ascending order.
+	Games []int
+}
+
+// PassRate returns the fraction of the tests which accepted H1.
+func (result SimulationResult) PassRate() float64 {
+	return float64(result.H1) / float64(len(result.Games))
+}
+
+// MeanGames returns the average number of games played by a test.
+func (result SimulationResult) MeanGames() float64 {
+	total := 0
+	for _, games := range result.Games {
+		total += games
+	}
+
+	return float64(total) / float64(len(result.Games))
+}
+
+// Percentile returns the number of games within which the given fraction of
+// the tests finished.
+func (result SimulationResult) Percentile(p float64) int {
+	i := int(math.Ceil(p*float64(len(result.Games)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return result.Games[i]
+}
+
+// SimulationWDL returns the probability of winning, drawing, and losing a
+// game for engines whose true elo difference, in the units of the
+// hypotheses of an SPRT, is elo, with the given ratio of the games drawn.
+func SimulationWDL(elo, draws float64, legacy bool) ([3]float64, error) {
+	if draws < 0 || draws >= 1 {
+		return [3]float64{}, errors.New("simulate: draw ratio is not in [0, 1)")
+	}
+
+	// draw elo of evenly matched engines with the given draw ratio
+	dlo := 400 * math.Log10((1+draws)/(1-draws))
+
+	if legacy {
+		w, d, l := eloToWDL(elo, dlo)
+		return [3]float64{w, d, l}, nil
+	}
+
+	// The normalized elo is converted to a score using the deviation of the
+	// score of a game pair made of independent games.
+	w, d, l := eloToWDL(0, dlo)
+	mu := nEloToScore(elo, math.Sqrt(variance(w, d, l)/2))
+
+	w = mu - draws/2
+	l = 1 - draws - w
+	if w < 0 || l < 0 {
+		return [3]float64{}, errors.New("simulate: elo is too large for the draw ratio")
+	}
+
+	return [3]float64{w, draws, l}, nil
+}
+
+// Run runs the simulation.
+func (simulation Simulation) Run() (SimulationResult, error) {
+	if simulation.Runs <= 0 {
+		return SimulationResult{}, errors.New("simulate: no tests to simulate")
+	}
+
+	if simulation.Elo0 >= simulation.Elo1 {
+		return SimulationResult{}, errors.New("simulate: elo0 is not less than elo1")
+	}
+
+	if simulation.Legacy && simulation.Penta != nil {
+		return SimulationResult{}, errors.New("simulate: trinomial tests need game results, not pair results")
+	}
+
+	if simulation.Penta != nil && len(simulation.Penta) != 5 {
+		return SimulationResult{}, errors.New("simulate: penta distribution needs 5 probabilities")
+	}
+
+	lower, upper := StoppingBounds(simulation.Alpha, simulation.Beta)
+
+	result := SimulationResult{Games: make([]int, simulation.Runs)}
+	conclusions := make([]int, simulation.Runs)
+
+	// The tests are split between the available processors, and each of
+	// them is simulated with its own generator so that the results only
+	// depend on the seed.
+	var wg sync.WaitGroup
+	workers := runtime.GOMAXPROCS(0)
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for run := worker; run < simulation.Runs; run += workers {
+				random := rand.New(rand.NewSource(simulation.Seed + int64(run)))
+				conclusions[run], result.Games[run] = simulation.test(random, lower, upper)
+			}
+		}(worker)
+	}
+
+	wg.Wait()
+
+	for _, conclusion := range conclusions {
+		switch conclusion {
+		case -1:
+			result.H0++
+		case +1:
+			result.H1++
+		default:
+			result.Unfinished++
+		}
+	}
+
+	sort.Ints(result.Games)
+	return result, nil
+}
+
+// test simulates a single test with the given stopping bounds, and returns
+// the hypothesis it accepted, as -1 for H0, +1 for H1, or 0 for none, and
+// the number of games it played.
+func (simulation Simulation) test(random *rand.Rand, lower, upper float64) (int, int) {
+	var penta [5]int // loss-loss to win-win
+	var wdl [3]int   // wins, draws, and losses
+
+	games := 0
+	for simulation.MaxGames == 0 || games+2 <= simulation.MaxGames {
+		games += 2
+		if simulation.Penta != nil {
+			penta[sample(random, simulation.Penta)]++
+		} else {
+			// A pair's result is its total score as an index, and is
+			// 2 for a win-loss as well as for a draw-draw.
+			first, second := sample(random, simulation.WDL[:]), sample(random, simulation.WDL[:])
+			wdl[first]++
+			wdl[second]++
+			penta[4-first-second]++
+		}
+
+		var llr float64
+		if simulation.Legacy {
+			llr = SPRT(wdl[0], wdl[1], wdl[2], simulation.Elo0, simulation.Elo1)
+		} else {
+			llr = PentaSPRT(penta[0], penta[1], penta[2], penta[3], penta[4], simulation.Elo0, simulation.Elo1)
+		}
+
+		switch {
+		case llr <= lower:
+			return -1, games
+		case llr >= upper:
+			return +1, games
+		}
+	}
+
+	return 0, games
+}
+
+// sample returns a random index drawn from the given probabilities, which
+// need not be normalized.
+func sample(random *rand.Rand, probabilities []float64) int {
+	total := 0.0
+	for _, p := range probabilities {
+		total += p
+	}
+
+	x := random.Float64() * total
+	for i, p := range probabilities {
+		if x < p {
+			return i
+		}
+
+		x -= p
+	}
+
+	return len(probabilities) - 1
+}
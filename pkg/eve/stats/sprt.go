@@ -47,3 +47,14 @@ func Elo(ws, ds, ls int) (muMin float64, mu float64, muMax float64) {
 
 	return clampElo(muMin), clampElo(mu), clampElo(muMax)
 }
+
+// LOS returns the likelihood of superiority of the target player, which is
+// the probability that it is stronger than its opponent, given its wins and
+// losses. Draws don't affect it.
+func LOS(ws, ls int) float64 {
+	if ws+ls == 0 {
+		return 0.5
+	}
+
+	return 0.5 + 0.5*math.Erf(float64(ws-ls)/math.Sqrt(2*float64(ws+ls)))
+}
@@ -53,3 +53,7 @@ func phiInv(p float64) float64 {
 func nEloToScore(nelo, r float64) float64 {
 	return nelo*math.Sqrt2*r/(800/math.Ln10) + 0.5
 }
+
+func scoreToNElo(score, r float64) float64 {
+	return (score - 0.5) * (800 / math.Ln10) / (math.Sqrt2 * r)
+}
@@ -0,0 +1,194 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// runsBucket is the bucket the records of the finished runs are stored in,
+// keyed by their names.
+var runsBucket = []byte("runs")
+
+var ErrNoRun = errors.New("store: no such run")
+
+// RunRecord is the record of a finished run, with its final results.
+type RunRecord struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Game string `json:"game"`
+
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+
+	// Games is the number of games played in the run.
+	Games int `json:"games"`
+
+	// Engines stores the results of each engine of the run.
+	Engines []EngineRecord `json:"engines"`
+
+	// TimeOdds is the ratio of the time of the run's first engine to that
+	// of the others, if the run was played with time odds.
+	TimeOdds float64 `json:"time_odds,omitempty"`
+
+	// SPRT stores the results of the run if it is an SPRT, and Match if it
+	// is a fixed-games match.
+	SPRT  *SPRTRecord  `json:"sprt,omitempty"`
+	Match *MatchRecord `json:"match,omitempty"`
+}
+
+// EngineRecord is the record of the results of an engine in a run.
+type EngineRecord struct {
+	// Name is the engine's name in the run's configuration, and ID is the
+	// name the engine reported for itself, which usually has its version.
+	Name string `json:"name"`
+	ID   string `json:"id,omitempty"`
+
+	// TimeC is the time control the engine played the run with.
+	TimeC string `json:"tc,omitempty"`
+
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+	Draws  int `json:"draws"`
+
+	// Elo is the engine's elo and Error its 95% error margin.
+	Elo   float64 `json:"elo"`
+	Error float64 `json:"error"`
+}
+
+// SPRTRecord is the record of the results of an SPRT, which are from the
+// point of view of its first engine. Penta stores the number of pairs with
+// each result, from loss-loss to win-win.
+type SPRTRecord struct {
+	Elo0  float64 `json:"elo0"`
+	Elo1  float64 `json:"elo1"`
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
+
+	LLR   float64 `json:"llr"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+
+	Penta [5]int `json:"penta"`
+
+	// Legacy is set if the LLR was computed with the trinomial model.
+	Legacy bool `json:"legacy,omitempty"`
+
+	// Conclusion is the accepted hypothesis, "H0" or "H1".
+	Conclusion string `json:"conclusion"`
+}
+
+// MatchRecord is the record of the results of a fixed-games match, which
+// are from the point of view of its first engine. Penta stores the number of
+// pairs with each result, from loss-loss to win-win.
+type MatchRecord struct {
+	Pairs int    `json:"pairs"`
+	Penta [5]int `json:"penta"`
+
+	// PentaElo is the elo estimated from the pair results and NElo the
+	// normalized elo, along with their 95% error margins.
+	PentaElo   float64 `json:"penta_elo"`
+	PentaError float64 `json:"penta_error"`
+	NElo       float64 `json:"nelo"`
+	NEloError  float64 `json:"nelo_error"`
+
+	// LOS is the likelihood of superiority of the first engine, and Draws
+	// the ratio of the games which were drawn.
+	LOS   float64 `json:"los"`
+	Draws float64 `json:"draws"`
+}
+
+// HasEngine reports if the run has an engine whose name or reported name
+// contains the given string.
+func (run RunRecord) HasEngine(name string) bool {
+	for _, engine := range run.Engines {
+		if strings.Contains(engine.Name, name) || strings.Contains(engine.ID, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SaveRun stores the record of a finished run, replacing any previous
+// record of a run with the same name.
+func (store *Store) SaveRun(run RunRecord) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	return store.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(runsBucket)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(run.Name), data)
+	})
+}
+
+// Run returns the record of the run with the given name.
+func (store *Store) Run(name string) (RunRecord, error) {
+	var run RunRecord
+	err := store.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(runsBucket)
+		if bucket == nil {
+			return ErrNoRun
+		}
+
+		data := bucket.Get([]byte(name))
+		if data == nil {
+			return ErrNoRun
+		}
+
+		return json.Unmarshal(data, &run)
+	})
+
+	return run, err
+}
+
+// Runs returns the records of every finished run, in the order they were
+// finished in.
+func (store *Store) Runs() ([]RunRecord, error) {
+	var runs []RunRecord
+	err := store.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(runsBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, data []byte) error {
+			var run RunRecord
+			if err := json.Unmarshal(data, &run); err != nil {
+				return err
+			}
+
+			runs = append(runs, run)
+			return nil
+		})
+	})
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Finished.Before(runs[j].Finished)
+	})
+
+	return runs, err
+}
@@ -0,0 +1,220 @@
+// Copyright © 2024 Rak Laptudirm <rak@laptudirm.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package store implements an embedded database which stores the games
+// played by every run, so that they can be queried and reported on later.
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	arbiter "laptudirm.com/x/arbiter/pkg/common"
+	"laptudirm.com/x/arbiter/pkg/eve/match"
+)
+
+// DefaultPath is the path of the database used by default.
+var DefaultPath = filepath.Join(arbiter.Directory, "results.db")
+
+// gamesBucket is the bucket the games are stored in, keyed by the order in
+// which they were added.
+var gamesBucket = []byte("games")
+
+// Store is a database of games. The database file is only opened while it
+// is being accessed, so that multiple runs can share it.
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Game is a game stored in the database.
+type Game struct {
+	// Run is the name of the run the game was played in, and Kind is the
+	// kind of that run, which is "tournament", "sprt", or "match".
+	Run  string `json:"run"`
+	Kind string `json:"kind"`
+
+	// Round, Number, and Pair identify the game within its run. Pair is the
+	// number of the game pair of an SPRT the game belongs to.
+	Round  int `json:"round,omitempty"`
+	Number int `json:"number"`
+	Pair   int `json:"pair,omitempty"`
+
+	// Players stores the indices of the game's engines among the engines
+	// of its run.
+	Players [2]int `json:"players"`
+
+	Game    string    `json:"game"`
+	Engines [2]Engine `json:"engines"`
+	Opening string    `json:"opening"`
+	Moves   []string  `json:"moves"`
+
+	// Result is from the point of view of the first engine, and White is
+	// the index of the engine which played White.
+	Result match.Result `json:"result"`
+	Reason string       `json:"reason"`
+	White  int          `json:"white"`
+
+	// WhiteTC and BlackTC are the time controls of the engines which played
+	// White and Black, which differ in games played with time odds.
+	WhiteTC string `json:"white_tc,omitempty"`
+	BlackTC string `json:"black_tc,omitempty"`
+
+	// Time stores the time usage statistics of each engine in the game.
+	Time [2]match.TimeUsage `json:"time"`
+
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// Engine is an engine which played a stored game.
+type Engine struct {
+	// Name is the engine's name in the run's configuration, and ID is the
+	// name the engine reported for itself, which usually has its version.
+	Name string `json:"name"`
+	ID   string `json:"id,omitempty"`
+
+	Protocol string            `json:"protocol,omitempty"`
+	TimeC    string            `json:"tc,omitempty"`
+	Depth    int               `json:"depth,omitempty"`
+	Nodes    int               `json:"nodes,omitempty"`
+	Options  map[string]string `json:"options,omitempty"`
+}
+
+// NewGame creates a Game from the configuration and outcome of a game.
+func NewGame(config *match.Config, outcome match.Outcome) Game {
+	game := Game{
+		Game:    config.Game,
+		Opening: match.Position{FEN: config.PositionFEN, Moves: config.OpeningMoves}.String(),
+		Moves:   outcome.Moves,
+		Result:  outcome.Result,
+		Reason:  outcome.Reason,
+		White:   outcome.White,
+		Time:    outcome.Time,
+	}
+
+	for i, engine := range config.Engines {
+		game.Engines[i] = Engine{
+			Name:     engine.Name,
+			ID:       outcome.Engines[i],
+			Protocol: engine.Protocol,
+			TimeC:    engine.TimeC,
+			Depth:    engine.Depth,
+			Nodes:    engine.Nodes,
+			Options:  engine.Options,
+		}
+	}
+
+	game.WhiteTC = config.Engines[outcome.White].TimeC
+	game.BlackTC = config.Engines[outcome.White^1].TimeC
+	return game
+}
+
+// Open returns a Store for the database at the given path, which is created
+// if it doesn't exist.
+func Open(path string) (*Store, error) {
+	store := &Store{path: path}
+	return store, store.update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(gamesBucket)
+		return err
+	})
+}
+
+// Add adds a game to the database. It is safe to call from multiple
+// goroutines.
+func (store *Store) Add(game Game) error {
+	data, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+
+	return store.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(gamesBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		var key [8]byte
+		binary.BigEndian.PutUint64(key[:], id)
+		return bucket.Put(key[:], data)
+	})
+}
+
+// Games returns the games in the database, in the order they were added,
+// which are selected by the given function. Every game is returned if the
+// function is nil.
+func (store *Store) Games(selected func(Game) bool) ([]Game, error) {
+	var games []Game
+	err := store.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).ForEach(func(_, data []byte) error {
+			var game Game
+			if err := json.Unmarshal(data, &game); err != nil {
+				return err
+			}
+
+			if selected == nil || selected(game) {
+				games = append(games, game)
+			}
+
+			return nil
+		})
+	})
+
+	return games, err
+}
+
+// Run returns a function which selects the games of the run with the given
+// name, for use with Games.
+func Run(name string) func(Game) bool {
+	return func(game Game) bool {
+		return game.Run == name
+	}
+}
+
+// lockTimeout is the time for which a Store waits for other runs to release
+// the database before giving up.
+const lockTimeout = 10 * time.Second
+
+func (store *Store) update(fn func(*bolt.Tx) error) error {
+	return store.open(false, func(db *bolt.DB) error {
+		return db.Update(fn)
+	})
+}
+
+func (store *Store) view(fn func(*bolt.Tx) error) error {
+	return store.open(true, func(db *bolt.DB) error {
+		return db.View(fn)
+	})
+}
+
+// open opens the database for the duration of the given function.
+func (store *Store) open(readOnly bool, fn func(*bolt.DB) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	db, err := bolt.Open(store.path, 0644, &bolt.Options{
+		Timeout:  lockTimeout,
+		ReadOnly: readOnly,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+	return fn(db)
+}
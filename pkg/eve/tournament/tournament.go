@@ -16,10 +16,15 @@ package tournament
 import (
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"laptudirm.com/x/arbiter/pkg/eve/dashboard"
+	"laptudirm.com/x/arbiter/pkg/eve/events"
 	"laptudirm.com/x/arbiter/pkg/eve/match"
+	"laptudirm.com/x/arbiter/pkg/eve/match/games"
 	"laptudirm.com/x/arbiter/pkg/eve/stats"
+	"laptudirm.com/x/arbiter/pkg/eve/store"
 	"laptudirm.com/x/arbiter/pkg/eve/tournament/schedule"
 )
 
@@ -31,9 +36,31 @@ func NewTournament(config Config) (*Tournament, error) {
 		Losses int
 		Draws  int
 	}, len(config.Engines))
+	tour.Time = make([]match.TimeUsage, len(config.Engines))
+	tour.ids = make([]string, len(config.Engines))
+	tour.openingResults.MinGames = config.OpeningsMinGames
+
+	// Tournaments without a name are named by their starting time, which
+	// identifies their games in the results database.
+	if tour.Config.Name == "" {
+		tour.Config.Name = time.Now().Format("tournament-20060102-150405")
+	}
+
+	// Games need to be adjudicated either by a built-in oracle or by an
+	// engine acting as a referee.
+	if games.GetOracle(config.Game) == nil && !match.HasReferee(config.Engines...) {
+		return nil, fmt.Errorf("new tour: no oracle or referee for game %s", config.Game)
+	}
 
+	// The engines are given time odds when their games are created, so that
+	// the config is unchanged.
 	var err error
-	tour.openings, err = match.NewBook(config.Openings)
+	tour.engines, err = match.TimeOdds(config.Engines, config.TimeOdds)
+	if err != nil {
+		return nil, err
+	}
+
+	tour.openings, err = match.NewBook(config.Game, config.Openings)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +83,12 @@ type Tournament struct {
 	Scheduler schedule.Scheduler
 	openings  *match.OpeningBook
 
+	// engines are the engines' configs, with time odds applied.
+	engines []match.EngineConfig
+
+	// Results of the games played with each opening.
+	openingResults match.OpeningResults
+
 	games    chan *Match
 	results  chan Result
 	complete chan bool
@@ -64,19 +97,83 @@ type Tournament struct {
 	Scores []struct {
 		Wins, Losses, Draws int
 	}
+
+	// Time usage statistics of each engine.
+	Time []match.TimeUsage
+
+	// Events is the stream the tournament's events are emitted to, if any.
+	Events *events.Stream
+
+	// Store is the database the tournament's games are stored in, if any.
+	Store *store.Store
+
+	// Dashboard is the live dashboard the tournament is shown on, if any.
+	Dashboard *dashboard.Dashboard
+
+	// started is the time at which the tournament was started, and ids
+	// stores the names the engines reported for themselves.
+	started time.Time
+	ids     []string
+
+	// stored are the games of an earlier run of the tournament by their
+	// round and number, and restored is the number of them which were
+	// counted instead of being played again.
+	stored   map[[2]int]store.Game
+	restored int
 }
 
 func (tour *Tournament) Start() error {
+	tour.started = time.Now()
+
+	// Games which were played before the tournament was restarted are
+	// counted without being played again.
+	pending := []*Match{}
+	for _, game := range tour.schedule() {
+		stored, found := tour.stored[[2]int{game.Round, game.Number}]
+		if found && stored.Players == [2]int{game.Player1, game.Player2} {
+			tour.restored++
+			tour.record(Result{
+				Match:   game,
+				Result:  stored.Result,
+				Reason:  stored.Reason,
+				White:   stored.White,
+				Time:    stored.Time,
+				Engines: [2]string{stored.Engines[0].ID, stored.Engines[1].ID},
+			})
+
+			continue
+		}
+
+		pending = append(pending, game)
+	}
+
+	go tour.ResultHandler()
+	for i := 0; i < tour.Config.Concurrency; i++ {
+		go tour.Thread(i)
+	}
+
+	for _, game := range pending {
+		tour.games <- game
+	}
+
+	close(tour.games)
+	<-tour.complete
+
+	return nil
+}
+
+// schedule returns all the games of the tournament in the order in which
+// they are played.
+func (tour *Tournament) schedule() []*Match {
 	// 1 Tournament = {ROUNDS} Rounds
 	// 1 Round      = {SOME_N} Encounters
 	// 1 Encounter  = {GAME_P} Game Pairs
 	// 1 Game Pair  = 2 Games
 
-	go tour.ResultHandler()
-	for i := 0; i < tour.Config.Concurrency; i++ {
-		go tour.Thread()
-	}
+	var matches []*Match
 
+	// Consecutive games are played with the same opening.
+	played, repeat := 0, tour.Config.Openings.GamesPerOpening()
 	for round := 0; round < tour.Config.Rounds; round++ {
 		tour.Scheduler.Initialize(len(tour.Config.Engines))
 
@@ -85,41 +182,66 @@ func (tour *Tournament) Start() error {
 
 			for pair := 0; pair < tour.Config.GamePairs; pair++ {
 				for game := 0; game < 2; game++ {
-					tour.games <- &Match{
+					// Games are numbered consecutively within each round, so
+					// that their names are unique.
+					number := (encounter*tour.Config.GamePairs+pair)*2 + game + 1
+					opening := tour.openings.Current()
+					matches = append(matches, &Match{
 						Config: match.Config{
-							Game:        tour.Config.Game,
-							PositionFEN: tour.openings.Current(),
+							Game:         tour.Config.Game,
+							PositionFEN:  opening.FEN,
+							OpeningMoves: opening.Moves,
+							Name:         fmt.Sprintf("round-%d-game-%d", round+1, number),
 							Engines: [2]match.EngineConfig{
-								tour.Config.Engines[p1],
-								tour.Config.Engines[p2],
+								tour.engines[p1],
+								tour.engines[p2],
 							},
 						},
 
-						Round:  round + 1,
-						Number: encounter*tour.Scheduler.TotalEncounters() + pair*2 + game + 1,
+						Round:   round + 1,
+						Number:  number,
+						Opening: opening,
 
 						Player1: p1,
 						Player2: p2,
-					}
+					})
 
 					// Switch turn.
 					p1, p2 = p2, p1
-				}
 
-				tour.openings.Next()
+					if played++; played%repeat == 0 {
+						tour.openings.Next()
+					}
+				}
 			}
 		}
 	}
 
-	close(tour.games)
-	<-tour.complete
+	return matches
+}
 
-	return nil
+// Restore makes the tournament count the given games, which were stored by
+// an earlier run of it, instead of playing them again. Games are matched by
+// their round and number, and stored games which don't match the players
+// scheduled for them are played again.
+func (tour *Tournament) Restore(games []store.Game) {
+	tour.stored = map[[2]int]store.Game{}
+	for _, game := range games {
+		key := [2]int{game.Round, game.Number}
+		if _, found := tour.stored[key]; !found {
+			tour.stored[key] = game
+		}
+	}
 }
 
-func (tour *Tournament) Thread() {
+func (tour *Tournament) Thread(worker int) {
+	// Engines are reused between the games played by a thread.
+	pool := match.NewPool(2)
+	defer pool.Close()
+
 	for game := range tour.games {
-		if err := tour.RunGame(game); err != nil {
+		game.Worker = worker
+		if err := tour.RunGame(game, pool); err != nil {
 			logrus.Error(err)
 		}
 	}
@@ -130,48 +252,111 @@ type Match struct {
 
 	Round, Number    int
 	Player1, Player2 int
+
+	// Opening is the opening the game is played with.
+	Opening match.Opening
+
+	// Worker is the index of the thread the game is played by.
+	Worker int
 }
 
-func (tour *Tournament) RunGame(game *Match) error {
+func (tour *Tournament) RunGame(game *Match, pool *match.Pool) error {
 	logrus.Infof(
 		"\x1b[33mStarting\x1b[0m Round #%d Game #%d: %s vs %s (\x1b[33m%s\x1b[0m)\n",
 		game.Round,
 		game.Number,
 		game.Engines[0].Name,
 		game.Engines[1].Name,
-		tour.openings.Current(),
+		match.Position{FEN: game.PositionFEN, Moves: game.OpeningMoves},
 	)
 
-	score, reason := match.Run(&game.Config)
+	id := events.Game{
+		Round:   game.Round,
+		Number:  game.Number,
+		Engines: [2]string{game.Engines[0].Name, game.Engines[1].Name},
+		Opening: game.Opening.String(),
+
+		TimeControls: [2]string{game.Engines[0].TimeC, game.Engines[1].TimeC},
+	}
+
+	tour.Events.Emit(events.GameStart, id)
+	tour.Dashboard.StartGame(
+		game.Worker,
+		fmt.Sprintf("Round #%d Game #%d", game.Round, game.Number),
+		id.Engines,
+	)
+
+	if tour.Dashboard != nil || (tour.Events != nil && tour.Events.Moves) {
+		game.OnMove = func(move match.MoveInfo) {
+			tour.Dashboard.Move(game.Worker, move)
+			tour.Events.Emit(events.Move, events.MoveData{
+				Game:   game.Number,
+				Ply:    move.Ply,
+				Engine: game.Engines[move.Engine].Name,
+				Move:   move.Move,
+				TimeMs: move.Elapsed.Milliseconds(),
+			})
+		}
+	}
 
-	tour.results <- Result{
+	started := time.Now()
+	outcome := match.Run(&game.Config, pool)
+
+	if tour.Store != nil {
+		record := store.NewGame(&game.Config, outcome)
+		record.Run, record.Kind = tour.Config.Name, "tournament"
+		record.Round, record.Number = game.Round, game.Number
+		record.Players = [2]int{game.Player1, game.Player2}
+		record.Started, record.Finished = started, time.Now()
+
+		if err := tour.Store.Add(record); err != nil {
+			logrus.Errorf("store game: %v", err)
+		}
+	}
+
+	end := events.GameEndData{
+		Game:   id,
+		White:  game.Engines[outcome.White].Name,
+		Result: outcome.Result.String(),
+		Reason: outcome.Reason,
+	}
+
+	switch outcome.Result {
+	case match.Win:
+		end.Winner = game.Engines[0].Name
+	case match.Loss:
+		end.Winner = game.Engines[1].Name
+	}
+
+	tour.Events.Emit(events.GameEnd, end)
+
+	result := Result{
 		Match:  game,
-		Result: score,
-		Reason: reason,
+		Result: outcome.Result,
+		Reason: outcome.Reason,
+		White:  outcome.White,
+		Time:   outcome.Time,
+
+		Engines: outcome.Engines,
 	}
 
+	tour.Dashboard.EndGame(game.Worker, fmt.Sprintf("Round #%d Game #%d: %s", game.Round, game.Number, result))
+	tour.results <- result
+
 	return nil
 }
 
 func (tour *Tournament) ResultHandler() {
-	result_count := 0
+	result_count := tour.restored
 	result_target := tour.Config.Rounds * tour.Scheduler.TotalEncounters() * tour.Config.GamePairs * 2
+	if result_count == result_target {
+		tour.finish(result_count)
+		return
+	}
+
 	for result := range tour.results {
 		result_count++
-
-		switch result.Result {
-		case match.Win:
-			tour.Scores[result.Match.Player1].Wins++
-			tour.Scores[result.Match.Player2].Losses++
-
-		case match.Loss:
-			tour.Scores[result.Match.Player2].Wins++
-			tour.Scores[result.Match.Player1].Losses++
-
-		case match.Draw:
-			tour.Scores[result.Match.Player1].Draws++
-			tour.Scores[result.Match.Player2].Draws++
-		}
+		tour.record(result)
 
 		logrus.Infof(
 			"\x1b[32mFinished\x1b[0m Round #%d Game #%d: %s vs %s: %s\n",
@@ -184,49 +369,216 @@ func (tour *Tournament) ResultHandler() {
 
 		if result_count%5 == 0 {
 			tour.Report()
+			tour.EmitStats(result_count)
+		}
+
+		if tour.Dashboard != nil {
+			// The remaining time is estimated from the games played by this
+			// run only, and not the restored ones.
+			elapsed := time.Since(tour.started)
+			remaining := elapsed / time.Duration(result_count-tour.restored) * time.Duration(result_target-result_count)
+
+			tour.Dashboard.SetTable(tour.table())
+			tour.Dashboard.SetProgress(
+				float64(result_count)/float64(result_target),
+				fmt.Sprintf("%d/%d games", result_count, result_target),
+				remaining,
+			)
 		}
 
 		if result_count == result_target {
-			close(tour.results)
-			tour.complete <- true
+			tour.finish(result_count)
 			return
 		}
 	}
 
 }
 
+// record adds the result of a game to the tournament's statistics.
+func (tour *Tournament) record(result Result) {
+	tour.Time[result.Match.Player1].Add(result.Time[0])
+	tour.Time[result.Match.Player2].Add(result.Time[1])
+	tour.openingResults.Add(result.Match.Opening, result.Result, result.White)
+	tour.ids[result.Match.Player1] = result.Engines[0]
+	tour.ids[result.Match.Player2] = result.Engines[1]
+
+	switch result.Result {
+	case match.Win:
+		tour.Scores[result.Match.Player1].Wins++
+		tour.Scores[result.Match.Player2].Losses++
+
+	case match.Loss:
+		tour.Scores[result.Match.Player2].Wins++
+		tour.Scores[result.Match.Player1].Losses++
+
+	case match.Draw:
+		tour.Scores[result.Match.Player1].Draws++
+		tour.Scores[result.Match.Player2].Draws++
+	}
+}
+
+// finish reports and stores the results of the tournament, which has
+// finished after the given number of games.
+func (tour *Tournament) finish(games int) {
+	// The final reports are shown after the dashboard is closed.
+	tour.Dashboard.Stop()
+
+	tour.Report()
+	tour.ReportTime()
+	tour.ReportOpenings()
+
+	tour.EmitStats(games)
+	tour.SaveRun(games)
+	tour.Events.Emit(events.RunEnd, events.RunEndData{Kind: "tournament", Games: games})
+
+	close(tour.results)
+	tour.complete <- true
+}
+
 func (tour *Tournament) Report() {
-	fmt.Println("╔══════════════════════════════════════════════════════════╗")
-	fmt.Println("║    Name               Elo Error   Wins Loss Draw   Total ║")
-	fmt.Println("╠══════════════════════════════════════════════════════════╣")
+	for _, line := range tour.table() {
+		fmt.Println(line)
+	}
+}
+
+// table returns the lines of the table of the engines' results.
+func (tour *Tournament) table() []string {
+	lines := []string{
+		"╔══════════════════════════════════════════════════════════╗",
+		"║    Name               Elo Error   Wins Loss Draw   Total ║",
+		"╠══════════════════════════════════════════════════════════╣",
+	}
+
 	for i, engine := range tour.Config.Engines {
 		score := tour.Scores[i]
 		lower, elo, upper := stats.Elo(score.Wins, score.Draws, score.Losses)
 
-		format := "║ %2d. %-15s   %+4.0f %4.0f   %4d %4d %4d   %5d ║\n"
+		format := "║ %2d. %-15s   %+4.0f %4.0f   %4d %4d %4d   %5d ║"
 		if tour.Config.Scheduler == "gauntlet" && i == 0 {
 			if elo >= 0 {
-				format = "║ \x1b[32m%2d. %-15s   %+4.0f %4.0f   %4d %4d %4d   %5d\x1b[0m ║\n"
+				format = "║ \x1b[32m%2d. %-15s   %+4.0f %4.0f   %4d %4d %4d   %5d\x1b[0m ║"
 			} else {
-				format = "║ \x1b[31m%2d. %-15s   %+4.0f %4.0f   %4d %4d %4d   %5d\x1b[0m ║\n"
+				format = "║ \x1b[31m%2d. %-15s   %+4.0f %4.0f   %4d %4d %4d   %5d\x1b[0m ║"
 			}
 		}
 
-		fmt.Printf(
+		lines = append(lines, fmt.Sprintf(
 			format,
 			i+1, engine.Name,
 			elo, math.Abs(math.Max(upper-elo, elo-lower)),
 			score.Wins, score.Losses, score.Draws,
-			score.Wins+score.Losses+score.Draws)
+			score.Wins+score.Losses+score.Draws))
+	}
+
+	return append(lines, "╚══════════════════════════════════════════════════════════╝")
+}
+
+// EmitStats emits the standings of the engines after the given number of
+// games to the tournament's event stream.
+func (tour *Tournament) EmitStats(games int) {
+	if tour.Events == nil {
+		return
+	}
+
+	data := events.TournamentStats{Games: games}
+	for i, engine := range tour.Config.Engines {
+		score := tour.Scores[i]
+		lower, elo, upper := stats.Elo(score.Wins, score.Draws, score.Losses)
+		data.Standings = append(data.Standings, events.Standing{
+			Name:   engine.Name,
+			Elo:    elo,
+			Error:  math.Abs(math.Max(upper-elo, elo-lower)),
+			Wins:   score.Wins,
+			Losses: score.Losses,
+			Draws:  score.Draws,
+		})
+	}
+
+	tour.Events.Emit(events.Stats, data)
+}
+
+// SaveRun stores the record of the tournament, which has finished after the
+// given number of games, in its results database.
+func (tour *Tournament) SaveRun(games int) {
+	if tour.Store == nil {
+		return
+	}
+
+	run := store.RunRecord{
+		Name:     tour.Config.Name,
+		Kind:     "tournament",
+		Game:     tour.Config.Game,
+		Started:  tour.started,
+		Finished: time.Now(),
+		Games:    games,
+		TimeOdds: tour.Config.TimeOdds,
+	}
+
+	for i, engine := range tour.Config.Engines {
+		score := tour.Scores[i]
+		lower, elo, upper := stats.Elo(score.Wins, score.Draws, score.Losses)
+		run.Engines = append(run.Engines, store.EngineRecord{
+			Name:   engine.Name,
+			ID:     tour.ids[i],
+			TimeC:  tour.engines[i].TimeC,
+			Wins:   score.Wins,
+			Losses: score.Losses,
+			Draws:  score.Draws,
+			Elo:    elo,
+			Error:  math.Abs(math.Max(upper-elo, elo-lower)),
+		})
+	}
+
+	if err := tour.Store.SaveRun(run); err != nil {
+		logrus.Errorf("store run: %v", err)
+	}
+}
+
+// ReportTime prints the time usage statistics of the engines.
+func (tour *Tournament) ReportTime() {
+	fmt.Println("╔══════════════════════════════════════════════════════════╗")
+	fmt.Println("║    Name                Avg Time   Max Time   Over  Flag  ║")
+	fmt.Println("╠══════════════════════════════════════════════════════════╣")
+	for i, engine := range tour.Config.Engines {
+		usage := tour.Time[i]
+		fmt.Printf(
+			"║ %2d. %-15s   %9s  %9s   %4d  %4d  ║\n",
+			i+1, engine.Name,
+			usage.Average().Round(time.Millisecond),
+			usage.Max.Round(time.Millisecond),
+			usage.Overruns, usage.Forfeits,
+		)
 	}
 	fmt.Println("╚══════════════════════════════════════════════════════════╝")
 }
 
+// ReportOpenings prints the results of the games played with each opening,
+// and stores them in the OpeningsOut file if it is set.
+func (tour *Tournament) ReportOpenings() {
+	tour.openingResults.Report()
+	if tour.Config.OpeningsOut == "" {
+		return
+	}
+
+	if err := tour.openingResults.WriteCSV(tour.Config.OpeningsOut); err != nil {
+		logrus.Error(err)
+	}
+}
+
 type Result struct {
 	Match *Match
 
 	Result match.Result
 	Reason string
+
+	// White is the index of the engine which played White.
+	White int
+
+	// Time usage statistics of the engines in the order they played.
+	Time [2]match.TimeUsage
+
+	// Names the engines reported for themselves in the order they played.
+	Engines [2]string
 }
 
 func (result Result) String() string {
@@ -243,6 +595,9 @@ func (result Result) String() string {
 }
 
 type Config struct {
+	// Name identifies the tournament's games in the results database.
+	Name string `yaml:"name"`
+
 	// The engines participating in the tournament.
 	Engines []match.EngineConfig `yaml:"engines"`
 
@@ -281,6 +636,18 @@ type Config struct {
 	PGNOut string // File to store the game PGNs at.
 	EPDOut string // File to store the game ends EPD at.
 
+	OpeningsOut string `yaml:"openings-out"` // File to store the results of each opening at.
+
+	// OpeningsMinGames is the number of games an opening has to be played
+	// in before it is reported as lopsided, which is 6 if it is zero.
+	OpeningsMinGames int `yaml:"openings-min-games"`
+
+	// TimeOdds is the ratio of the time given to the first engine to that
+	// given to the others, which play with their own time controls. Only
+	// the first engine is given odds, in all of its games, and the games
+	// between the other engines are played without odds.
+	TimeOdds float64 `yaml:"time-odds"`
+
 	// Restart a crashed engine instead of stopping the match.
 	Recover bool
 }